@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"order-service/internal/model"
+	"strings"
+	"time"
+)
+
+type profile struct {
+	locale   string
+	currency string
+	cities   []string
+	region   string
+	phone    string
+	provider string
+	bank     string
+	service  string
+	entry    string
+}
+
+var profiles = []profile{
+	{"en", "USD", []string{"New York", "Chicago", "Austin"}, "US", "+1202555", "stripe", "chase", "usps", "USPS"},
+	{"ru", "RUB", []string{"Moscow", "Kazan", "Omsk"}, "RU", "+7916123", "sberpay", "sber", "russian-post", "RUEXP"},
+	{"de", "EUR", []string{"Berlin", "Hamburg", "Munich"}, "DE", "+4930123", "paypal", "deutsche", "dhl", "DHL"},
+	{"kk-KZ", "KZT", []string{"Almaty", "Astana"}, "KZ", "+7701123", "kaspi", "kaspi", "kazpost", "KZP"},
+	{"en-GB", "GBP", []string{"London", "Leeds"}, "GB", "+4420712", "wbpay", "barclays", "royal-mail", "RM"},
+}
+
+var (
+	firstNames = []string{"Anna", "Ivan", "John", "Maria", "Olga", "Peter", "Aigerim", "Hans", "Emily"}
+	lastNames  = []string{"Smith", "Ivanova", "Petrov", "Muller", "Brown", "Nurlanova", "Clark"}
+	products   = []struct{ name, brand string }{
+		{"Mascaras", "Vivienne Sabo"}, {"Smartphone Case", "CaseMaster"}, {"Screen Protector", "GlassPro"},
+		{"USB-C Cable", "CableTech"}, {"Wireless Headphones", "AudioPro"}, {"Sneakers", "RunFast"},
+		{"T-Shirt", "Basic"}, {"Coffee Beans", "Roastery"}, {"Notebook", "PaperCo"}, {"Desk Lamp", "Lumen"},
+	}
+	sizes = []string{"0", "S", "M", "L", "XL", "One Size"}
+)
+
+// generator produces unique random orders that pass model.Order.Validate,
+// mixing in invalid ones at invalidRatio.
+type generator struct {
+	rnd          *rand.Rand
+	run          string
+	seq          uint64
+	invalidRatio float64
+}
+
+func newGenerator(seed uint64, invalidRatio float64) *generator {
+	return &generator{
+		rnd:          rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		run:          fmt.Sprintf("%08x", uint32(seed)),
+		invalidRatio: invalidRatio,
+	}
+}
+
+// emitAll emits generated messages until count is reached (0 for no limit)
+// or emit stops it.
+func (g *generator) emitAll(count int, emit func(message) error) error {
+	for i := 0; count == 0 || i < count; i++ {
+		if err := emit(g.next()); err != nil {
+			if errors.Is(err, errStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) next() message {
+	g.seq++
+	order := g.order()
+	source := "generated:" + order.OrderUID
+
+	if g.rnd.Float64() < g.invalidRatio {
+		return message{source: source + ":invalid", data: g.corrupt(order)}
+	}
+	data, _ := json.Marshal(order)
+	return message{source: source, data: data}
+}
+
+func (g *generator) order() *model.Order {
+	p := profiles[g.rnd.IntN(len(profiles))]
+	uid := fmt.Sprintf("%s%08xload", g.run, g.seq)
+	track := fmt.Sprintf("%sLOAD%09d", p.entry, g.rnd.IntN(1e9))
+	first, last := firstNames[g.rnd.IntN(len(firstNames))], lastNames[g.rnd.IntN(len(lastNames))]
+
+	items := make([]model.Item, 1+g.rnd.IntN(5))
+	goodsTotal := 0
+	for i := range items {
+		product := products[g.rnd.IntN(len(products))]
+		price := 100 + g.rnd.IntN(20000)
+		sale := []int{0, 0, 10, 20, 30, 50}[g.rnd.IntN(6)]
+		total := price * (100 - sale) / 100
+		items[i] = model.Item{
+			ChrtID:      1000000 + g.rnd.IntN(9000000),
+			TrackNumber: track,
+			Price:       price,
+			Rid:         fmt.Sprintf("%s%02d", uid, i),
+			Name:        product.name,
+			Sale:        sale,
+			Size:        sizes[g.rnd.IntN(len(sizes))],
+			TotalPrice:  total,
+			NmID:        1000000 + g.rnd.IntN(9000000),
+			Brand:       product.brand,
+			Status:      202,
+		}
+		goodsTotal += total
+	}
+	deliveryCost := []int{0, 300, 500, 1500}[g.rnd.IntN(4)]
+	customFee := []int{0, 0, 0, 100}[g.rnd.IntN(4)]
+	created := time.Now().Add(-time.Duration(g.rnd.IntN(30*24)) * time.Hour)
+
+	return &model.Order{
+		OrderUID:    uid,
+		TrackNumber: track,
+		Entry:       p.entry,
+		Delivery: model.Delivery{
+			Name:    first + " " + last,
+			Phone:   fmt.Sprintf("%s%04d", p.phone, g.rnd.IntN(10000)),
+			Zip:     fmt.Sprintf("%06d", g.rnd.IntN(1000000)),
+			City:    p.cities[g.rnd.IntN(len(p.cities))],
+			Address: fmt.Sprintf("Main st. %d", 1+g.rnd.IntN(200)),
+			Region:  p.region,
+			Email:   strings.ToLower(fmt.Sprintf("%s.%s%d@example.com", first, last, g.rnd.IntN(1000))),
+		},
+		Payment: model.Payment{
+			Transaction:  uid,
+			RequestID:    "",
+			Currency:     p.currency,
+			Provider:     p.provider,
+			Amount:       goodsTotal + deliveryCost + customFee,
+			PaymentDt:    created.Unix(),
+			Bank:         p.bank,
+			DeliveryCost: deliveryCost,
+			GoodsTotal:   goodsTotal,
+			CustomFee:    customFee,
+		},
+		Items:             items,
+		Locale:            p.locale,
+		InternalSignature: "",
+		CustomerID:        fmt.Sprintf("customer%d", g.rnd.IntN(10000)),
+		DeliveryService:   p.service,
+		Shardkey:          fmt.Sprint(g.rnd.IntN(10)),
+		SmID:              g.rnd.IntN(100),
+		DateCreated:       created,
+		OofShard:          fmt.Sprint(g.rnd.IntN(2)),
+	}
+}
+
+// corrupt breaks the order in one of the ways the service must reject:
+// undecodable JSON, a missing field or inconsistent totals.
+func (g *generator) corrupt(order *model.Order) []byte {
+	switch g.rnd.IntN(3) {
+	case 0:
+		data, _ := json.Marshal(order)
+		return data[:len(data)/2]
+	case 1:
+		order.TrackNumber = ""
+	default:
+		order.Payment.Amount++
+	}
+	data, _ := json.Marshal(order)
+	return data
+}
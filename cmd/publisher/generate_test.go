@@ -0,0 +1,71 @@
+package main
+
+import (
+	"order-service/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerator_ProducesValidUniqueOrders(t *testing.T) {
+	g := newGenerator(42, 0)
+	seen := make(map[string]bool)
+	currencies := make(map[string]bool)
+
+	err := g.emitAll(500, func(msg message) error {
+		var order model.Order
+		if err := order.FromJSON(msg.data); err != nil {
+			t.Fatalf("Generated order %s is invalid: %v", msg.source, err)
+		}
+		if seen[order.OrderUID] {
+			t.Fatalf("Duplicate order_uid %s", order.OrderUID)
+		}
+		seen[order.OrderUID] = true
+		currencies[order.Payment.Currency] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("emitAll: %v", err)
+	}
+	if len(seen) != 500 {
+		t.Errorf("Expected 500 orders, got %d", len(seen))
+	}
+	if len(currencies) < 3 {
+		t.Errorf("Expected varied currencies, got %v", currencies)
+	}
+}
+
+func TestGenerator_InvalidRatio(t *testing.T) {
+	g := newGenerator(7, 1)
+
+	err := g.emitAll(50, func(msg message) error {
+		if !strings.HasSuffix(msg.source, ":invalid") {
+			t.Fatalf("Expected only invalid messages, got %s", msg.source)
+		}
+		var order model.Order
+		if err := order.FromJSON(msg.data); err == nil {
+			t.Fatalf("Expected %s to fail validation", msg.source)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("emitAll: %v", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(100-i) * time.Millisecond
+	}
+
+	if got := percentile(latencies, 50); got != 50*time.Millisecond {
+		t.Errorf("Expected p50 of 50ms, got %s", got)
+	}
+	if got := percentile(latencies, 99); got != 99*time.Millisecond {
+		t.Errorf("Expected p99 of 99ms, got %s", got)
+	}
+	if got := percentile(latencies, 100); got != 100*time.Millisecond {
+		t.Errorf("Expected max of 100ms, got %s", got)
+	}
+}
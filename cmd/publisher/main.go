@@ -1,28 +1,38 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"math"
+	"order-service/internal/broker"
 	"order-service/internal/model"
 	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
 	"time"
-
-	"github.com/nats-io/stan.go"
 )
 
-type Config struct {
-	NatsClusterID string
-	NatsClientID  string
-	NatsChannel   string
-}
+type options struct {
+	broker      string
+	url         string
+	stream      string
+	cluster     string
+	client      string
+	channel     string
+	rate        float64
+	count       int
+	concurrency int
+	dryRun      bool
 
-func loadConfig() *Config {
-	return &Config{
-		NatsClusterID: getEnv("NATS_CLUSTER_ID", "test-cluster"),
-		NatsClientID:  getEnv("NATS_CLIENT_ID", "test-publisher"),
-		NatsChannel:   getEnv("NATS_CHANNEL", "orders"),
-	}
+	generate     bool
+	invalidRatio float64
+	seed         uint64
 }
 
 func getEnv(key, defaultValue string) string {
@@ -32,224 +42,275 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func main() {
-	cfg := loadConfig()
+func parseFlags() options {
+	var opts options
+	flag.StringVar(&opts.broker, "broker", getEnv("BROKER", broker.KindSTAN), "message broker: stan or jetstream")
+	flag.StringVar(&opts.url, "url", getEnv("NATS_URL", "nats://localhost:4222"), "NATS server URL")
+	flag.StringVar(&opts.stream, "stream", getEnv("NATS_STREAM", "ORDERS"), "JetStream stream, created if missing")
+	flag.StringVar(&opts.cluster, "cluster", getEnv("NATS_CLUSTER_ID", "test-cluster"), "NATS Streaming cluster ID")
+	flag.StringVar(&opts.client, "client", getEnv("NATS_CLIENT_ID", "test-publisher"), "NATS Streaming client ID")
+	flag.StringVar(&opts.channel, "channel", getEnv("NATS_CHANNEL", "orders"), "channel to publish to")
+	flag.Float64Var(&opts.rate, "rate", 0, "messages per second, 0 for as fast as acks allow")
+	flag.IntVar(&opts.count, "count", 0, "stop after this many messages, 0 for all input")
+	flag.IntVar(&opts.concurrency, "concurrency", 16, "maximum publishes awaiting an ack")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "validate orders without publishing")
+	flag.BoolVar(&opts.generate, "generate", false, "publish random orders until -count is reached or interrupted")
+	flag.Float64Var(&opts.invalidRatio, "invalid-ratio", 0, "share of generated messages that are invalid, 0 to 1")
+	flag.Uint64Var(&opts.seed, "seed", uint64(time.Now().UnixNano()), "random seed for -generate")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), `Usage: publisher [flags] [file.json | dir | -]...
 
-	log.Println("Starting publisher...")
-	log.Printf("NATS Cluster: %s", cfg.NatsClusterID)
-	log.Printf("NATS Channel: %s", cfg.NatsChannel)
+Publishes orders to NATS Streaming or JetStream. Each argument is a JSON file with one
+order or an array of orders, a directory of *.json files, or "-" for NDJSON
+on stdin. Without arguments the built-in sample orders are published.
+With -generate, random orders are published instead of any input.
 
-	sc, err := stan.Connect(cfg.NatsClusterID, cfg.NatsClientID)
-	if err != nil {
-		log.Fatalf("Failed to connect to NATS: %v", err)
+Flags:
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if opts.concurrency <= 0 {
+		log.Fatal("-concurrency must be positive")
+	}
+	if opts.rate < 0 || opts.count < 0 {
+		log.Fatal("-rate and -count must not be negative")
 	}
-	defer sc.Close()
+	if opts.invalidRatio < 0 || opts.invalidRatio > 1 {
+		log.Fatal("-invalid-ratio must be between 0 and 1")
+	}
+	return opts
+}
+
+func main() {
+	opts := parseFlags()
 
-	log.Println("Connected to NATS successfully!")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	orders := generateTestOrders()
+	p := &publisher{
+		opts:  opts,
+		ctx:   ctx,
+		slots: make(chan struct{}, opts.concurrency),
+		quiet: opts.generate,
+	}
+	if opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+		defer ticker.Stop()
+		p.limiter = ticker.C
+	}
 
-	for i, order := range orders {
-		data, err := json.MarshalIndent(order, "", "  ")
+	if !opts.dryRun {
+		conn, err := broker.Dial(broker.Config{
+			Kind:       opts.broker,
+			URL:        opts.url,
+			ClusterID:  opts.cluster,
+			ClientID:   opts.client,
+			Stream:     opts.stream,
+			MaxPending: opts.concurrency,
+		})
 		if err != nil {
-			log.Printf("Failed to marshal order %s: %v", order.OrderUID, err)
-			continue
+			log.Fatalf("Failed to connect to NATS: %v", err)
 		}
+		defer conn.Close()
+		p.conn = conn
+		log.Printf("Connected to %s at %s, publishing to %s", opts.broker, opts.url, opts.channel)
+	}
 
-		err = sc.Publish(cfg.NatsChannel, data)
+	start := time.Now()
+	var err error
+	switch {
+	case opts.generate:
+		log.Printf("Generating orders with seed %d", opts.seed)
+		go p.reportProgress(start)
+		err = newGenerator(opts.seed, opts.invalidRatio).emitAll(opts.count, p.send)
+	case flag.NArg() == 0:
+		err = publishSamples(p.send)
+	default:
+		err = readInputs(flag.Args(), os.Stdin, p.send)
+	}
+	p.wait()
+
+	s := p.stats()
+	elapsed := time.Since(start)
+	if opts.dryRun {
+		log.Printf("Done in %s: %d valid, %d invalid", elapsed.Round(time.Millisecond), s.valid, s.invalid)
+	} else {
+		log.Printf("Done in %s: %d sent, %d acked, %d failed, %.1f msg/s",
+			elapsed.Round(time.Millisecond), s.sent, s.acked, s.failed, float64(s.acked)/elapsed.Seconds())
+		if len(s.latencies) > 0 {
+			log.Printf("Publish latency: p50=%s p90=%s p99=%s max=%s",
+				percentile(s.latencies, 50), percentile(s.latencies, 90),
+				percentile(s.latencies, 99), percentile(s.latencies, 100))
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to read input: %v", err)
+	}
+	if s.failed > 0 || s.invalid > 0 {
+		os.Exit(1)
+	}
+}
+
+func publishSamples(emit func(message) error) error {
+	for _, order := range generateTestOrders() {
+		data, err := json.Marshal(order)
 		if err != nil {
-			log.Printf("Failed to publish order %s: %v", order.OrderUID, err)
-			continue
+			return err
 		}
+		if err := emit(message{source: "sample:" + order.OrderUID, data: data}); err != nil {
+			if errors.Is(err, errStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+type stats struct {
+	sent, acked, failed int
+	valid, invalid      int
+	latencies           []time.Duration
+}
+
+type publisher struct {
+	opts    options
+	ctx     context.Context
+	conn    broker.Publisher
+	limiter <-chan time.Time
+	slots   chan struct{}
+	pending sync.WaitGroup
+	quiet   bool
+
+	mu sync.Mutex
+	s  stats
+}
+
+// send publishes one message, or validates it in dry-run mode. It blocks
+// while -concurrency publishes await their acks and paces sends to -rate.
+func (p *publisher) send(msg message) error {
+	p.mu.Lock()
+	done := p.s.sent + p.s.valid + p.s.invalid
+	p.mu.Unlock()
+	if p.opts.count > 0 && done >= p.opts.count {
+		return errStop
+	}
+
+	if p.opts.dryRun {
+		p.validate(msg)
+		return nil
+	}
 
-		log.Printf("[%d/%d] Order %s published successfully!", i+1, len(orders), order.OrderUID)
-		fmt.Printf("Published order: %s, Track: %s\n", order.OrderUID, order.TrackNumber)
-		
-		if i < len(orders)-1 {
-			time.Sleep(2 * time.Second)
+	if p.limiter != nil {
+		select {
+		case <-p.limiter:
+		case <-p.ctx.Done():
+			return errStop
 		}
 	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.ctx.Done():
+		return errStop
+	}
+
+	p.pending.Add(1)
+	start := time.Now()
+	err := p.conn.PublishAsync(p.opts.channel, "", msg.data, func(guid string, err error) {
+		p.onAck(msg, guid, time.Since(start), err)
+	})
+	if err != nil {
+		<-p.slots
+		p.pending.Done()
+		p.mu.Lock()
+		p.s.sent++
+		p.s.failed++
+		p.mu.Unlock()
+		fmt.Printf("FAIL %s: %v\n", msg.source, err)
+		return nil
+	}
+
+	p.mu.Lock()
+	p.s.sent++
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *publisher) onAck(msg message, guid string, latency time.Duration, err error) {
+	defer p.pending.Done()
+	<-p.slots
 
-	log.Println("All orders published successfully!")
-	fmt.Println("Publisher finished work. Check the server logs for incoming orders.")
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.s.failed++
+		fmt.Printf("FAIL %s %s: %v\n", msg.source, guid, err)
+		return
+	}
+	p.s.acked++
+	p.s.latencies = append(p.s.latencies, latency)
+	if !p.quiet {
+		fmt.Printf("ACK  %s %s\n", msg.source, guid)
+	}
 }
 
-func generateTestOrders() []model.Order {
-	now := time.Now()
-	
-	return []model.Order{
-		{
-			OrderUID:    "b563feb7b2b84b6test",
-			TrackNumber: "WBILMTESTTRACK",
-			Entry:       "WBIL",
-			Delivery: model.Delivery{
-				Name:    "Test Testov",
-				Phone:   "+9720000000",
-				Zip:     "2639809",
-				City:    "Kiryat Mozkin",
-				Address: "Ploshad Mira 15",
-				Region:  "Kraiot",
-				Email:   "test@gmail.com",
-			},
-			Payment: model.Payment{
-				Transaction:  "b563feb7b2b84b6test",
-				RequestID:    "",
-				Currency:     "USD",
-				Provider:     "wbpay",
-				Amount:       1817,
-				PaymentDt:    1637907727,
-				Bank:         "alpha",
-				DeliveryCost: 1500,
-				GoodsTotal:   317,
-				CustomFee:    0,
-			},
-			Items: []model.Item{
-				{
-					ChrtID:      9934930,
-					TrackNumber: "WBILMTESTTRACK",
-					Price:       453,
-					Rid:         "ab4219087a764ae0btest",
-					Name:        "Mascaras",
-					Sale:        30,
-					Size:        "0",
-					TotalPrice:  317,
-					NmID:        2389212,
-					Brand:       "Vivienne Sabo",
-					Status:      202,
-				},
-			},
-			Locale:            "en",
-			InternalSignature: "",
-			CustomerID:        "test",
-			DeliveryService:   "meest",
-			Shardkey:          "9",
-			SmID:              99,
-			DateCreated:       now,
-			OofShard:          "1",
-		},
-		{
-			OrderUID:    "a462fec8c3c95c7demo",
-			TrackNumber: "RUEXP DEMO123",
-			Entry:       "RUEXP",
-			Delivery: model.Delivery{
-				Name:    "Ivan Ivanov",
-				Phone:   "+79161234567",
-				Zip:     "101000",
-				City:    "Moscow",
-				Address: "Tverskaya st. 10",
-				Region:  "Moscow",
-				Email:   "ivanov@mail.ru",
-			},
-			Payment: model.Payment{
-				Transaction:  "a462fec8c3c95c7demo",
-				RequestID:    "req_12345",
-				Currency:     "RUB",
-				Provider:     "sberpay",
-				Amount:       5420,
-				PaymentDt:    1637911127,
-				Bank:         "sber",
-				DeliveryCost: 500,
-				GoodsTotal:   4920,
-				CustomFee:    0,
-			},
-			Items: []model.Item{
-				{
-					ChrtID:      8847531,
-					TrackNumber: "RUEXP DEMO123",
-					Price:       2460,
-					Rid:         "cd5320198b875bf1demo",
-					Name:        "Smartphone Case",
-					Sale:        10,
-					Size:        "M",
-					TotalPrice:  2214,
-					NmID:        5421897,
-					Brand:       "CaseMaster",
-					Status:      202,
-				},
-				{
-					ChrtID:      8847532,
-					TrackNumber: "RUEXP DEMO123",
-					Price:       1500,
-					Rid:         "ef6431209c986cg2demo",
-					Name:        "Screen Protector",
-					Sale:        20,
-					Size:        "Universal",
-					TotalPrice:  1200,
-					NmID:        5421898,
-					Brand:       "GlassPro",
-					Status:      202,
-				},
-				{
-					ChrtID:      8847533,
-					TrackNumber: "RUEXP DEMO123",
-					Price:       1200,
-					Rid:         "gh7542310da097dh3demo",
-					Name:        "USB-C Cable",
-					Sale:        15,
-					Size:        "1m",
-					TotalPrice:  1020,
-					NmID:        5421899,
-					Brand:       "CableTech",
-					Status:      202,
-				},
-			},
-			Locale:            "ru",
-			InternalSignature: "demo_signature",
-			CustomerID:        "demo_user",
-			DeliveryService:   "russian-post",
-			Shardkey:          "5",
-			SmID:              88,
-			DateCreated:       now.Add(-1 * time.Hour),
-			OofShard:          "0",
-		},
-		{
-			OrderUID:    "c573ffd9d4da6d8sample",
-			TrackNumber: "USPS SAMPLE456",
-			Entry:       "USPS",
-			Delivery: model.Delivery{
-				Name:    "John Smith",
-				Phone:   "+12025550123",
-				Zip:     "10001",
-				City:    "New York",
-				Address: "5th Avenue 123",
-				Region:  "NY",
-				Email:   "john.smith@example.com",
-			},
-			Payment: model.Payment{
-				Transaction:  "c573ffd9d4da6d8sample",
-				RequestID:    "req_67890",
-				Currency:     "USD",
-				Provider:     "stripe",
-				Amount:       8999,
-				PaymentDt:    1637914527,
-				Bank:         "chase",
-				DeliveryCost: 0,
-				GoodsTotal:   8999,
-				CustomFee:    0,
-			},
-			Items: []model.Item{
-				{
-					ChrtID:      7756420,
-					TrackNumber: "USPS SAMPLE456",
-					Price:       8999,
-					Rid:         "hi8653421eb1a8ei4sample",
-					Name:        "Wireless Headphones",
-					Sale:        0,
-					Size:        "One Size",
-					TotalPrice:  8999,
-					NmID:        6654321,
-					Brand:       "AudioPro",
-					Status:      202,
-				},
-			},
-			Locale:            "en",
-			InternalSignature: "",
-			CustomerID:        "john_sample",
-			DeliveryService:   "usps",
-			Shardkey:          "3",
-			SmID:              77,
-			DateCreated:       now.Add(-2 * time.Hour),
-			OofShard:          "1",
-		},
-	}
-}
\ No newline at end of file
+func (p *publisher) validate(msg message) {
+	var order model.Order
+	err := order.FromJSON(msg.data)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.s.invalid++
+		fmt.Printf("INVALID %s: %v\n", msg.source, err)
+		return
+	}
+	p.s.valid++
+	if !p.quiet {
+		fmt.Printf("VALID   %s %s\n", msg.source, order.OrderUID)
+	}
+}
+
+// wait blocks until every published message has been acked or has failed.
+func (p *publisher) wait() {
+	p.pending.Wait()
+}
+
+func (p *publisher) stats() stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.s
+	s.latencies = slices.Clone(p.s.latencies)
+	return s
+}
+
+// reportProgress logs the publish rate every few seconds until interrupted.
+func (p *publisher) reportProgress(start time.Time) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	last := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.ctx.Done():
+			return
+		}
+		p.mu.Lock()
+		acked, failed := p.s.acked, p.s.failed
+		p.mu.Unlock()
+		log.Printf("%s: %d acked (%.1f msg/s), %d failed",
+			time.Since(start).Round(time.Second), acked, float64(acked-last)/5, failed)
+		last = acked
+	}
+}
+
+// percentile returns the p-th percentile of latencies, nearest-rank.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)].Round(time.Microsecond)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"order-service/internal/broker"
+	"order-service/internal/broker/brokertest"
+	"testing"
+	"time"
+)
+
+func TestPublisherBrokers(t *testing.T) {
+	for _, kind := range broker.Kinds {
+		t.Run(kind, func(t *testing.T) {
+			conn, err := broker.Dial(brokertest.Run(t, kind))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			received := make(chan broker.Message, 10)
+			opts := broker.SubscribeOptions{Durable: "test", AckWait: time.Second, MaxInflight: 10}
+			if err := conn.Subscribe("orders", opts, func(msg broker.Message) { received <- msg }); err != nil {
+				t.Fatal(err)
+			}
+
+			p := &publisher{
+				opts:  options{channel: "orders", count: 3},
+				ctx:   context.Background(),
+				conn:  conn,
+				slots: make(chan struct{}, 2),
+				quiet: true,
+			}
+			if err := newGenerator(1, 0).emitAll(p.opts.count, p.send); err != nil {
+				t.Fatal(err)
+			}
+			p.wait()
+
+			if s := p.stats(); s.sent != 3 || s.acked != 3 || s.failed != 0 {
+				t.Fatalf("sent %d, acked %d, failed %d", s.sent, s.acked, s.failed)
+			}
+			for range 3 {
+				select {
+				case msg := <-received:
+					msg.Ack()
+				case <-time.After(5 * time.Second):
+					t.Fatal("published message not delivered")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errStop ends reading early without an error.
+var errStop = errors.New("stop")
+
+// message is one payload to publish and where it came from, for reporting.
+type message struct {
+	source string
+	data   []byte
+}
+
+// readInputs streams messages from the given paths, in order, to emit. A path
+// may be a JSON file holding one order or an array of orders, a directory
+// whose *.json files are read in name order, or "-" for NDJSON on stdin.
+// Returning errStop from emit stops reading.
+func readInputs(paths []string, stdin io.Reader, emit func(message) error) error {
+	for _, path := range paths {
+		var err error
+		if path == "-" {
+			err = readNDJSON("stdin", stdin, emit)
+		} else {
+			err = readPath(path, emit)
+		}
+		if errors.Is(err, errStop) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readPath(path string, emit func(message) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return readFile(path, emit)
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".json") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if err := readFile(file, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(path string, emit func(message) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '[' {
+		return emit(message{source: path, data: data})
+	}
+
+	var orders []json.RawMessage
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for i, order := range orders {
+		if err := emit(message{source: fmt.Sprintf("%s[%d]", path, i), data: order}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readNDJSON(name string, r io.Reader, emit func(message) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		err := emit(message{source: fmt.Sprintf("%s:%d", name, line), data: bytes.Clone(data)})
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
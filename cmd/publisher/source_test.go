@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadInputs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	single := write("single.json", `{"order_uid": "a"}`)
+	array := write("array.json", ` [{"order_uid": "b"}, {"order_uid": "c"}] `)
+	write("orders/2.json", `{"order_uid": "e"}`)
+	write("orders/1.json", `{"order_uid": "d"}`)
+	write("orders/notes.txt", `ignored`)
+	stdin := strings.NewReader("{\"order_uid\": \"f\"}\n\n{\"order_uid\": \"g\"}\n")
+
+	var sources []string
+	err := readInputs([]string{single, array, filepath.Join(dir, "orders"), "-"}, stdin, func(msg message) error {
+		sources = append(sources, strings.TrimPrefix(msg.source, dir+string(filepath.Separator)))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readInputs: %v", err)
+	}
+
+	want := []string{"single.json", "array.json[0]", "array.json[1]", "orders/1.json", "orders/2.json", "stdin:1", "stdin:3"}
+	if strings.Join(sources, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, sources)
+	}
+}
+
+func TestReadInputs_StopsEarly(t *testing.T) {
+	stdin := strings.NewReader("{}\n{}\n{}\n")
+
+	n := 0
+	err := readInputs([]string{"-"}, stdin, func(msg message) error {
+		n++
+		if n == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readInputs: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected reading to stop after 2 messages, got %d", n)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"order-service/internal/migrate"
+	"order-service/migrations"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runMigrate implements `server migrate up|down [steps]|status`.
+func runMigrate(databaseURL string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		log.Printf("Applied %d migration(s)", n)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive integer")
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		log.Printf("Rolled back %d migration(s)", n)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func migrateOnStart(databaseURL string) error {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	n, err := m.Up(context.Background())
+	if n > 0 {
+		log.Printf("Applied %d migration(s)", n)
+	}
+	return err
+}
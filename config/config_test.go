@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "nats_channel: from-file\nserver_port: \":9090\"\n"
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("NATS_CHANNEL", "from-env")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", cfg.NatsChannel)
+	assert.Equal(t, ":9090", cfg.ServerPort)
+	assert.Equal(t, "test-cluster", cfg.NatsClusterID)
+}
+
+func TestLoad_EmptyDisables(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	for _, key := range []string{"NATS_STATUS_CHANNEL", "NATS_REFUND_CHANNEL", "NATS_MONITOR_URL", "OUTBOX_SUBJECT"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("NATS_CHANNEL", "")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.NatsStatusChannel)
+	assert.Empty(t, cfg.NatsRefundChannel)
+	assert.Empty(t, cfg.NatsMonitorURL)
+	assert.Empty(t, cfg.OutboxSubject)
+	assert.Equal(t, "orders", cfg.NatsChannel, "required settings keep their default")
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	_, err := Load()
+	assert.Error(t, err)
+}
+
+func TestValidate_RequiredFields(t *testing.T) {
+	cfg := &Config{}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, name := range []string{"database_url", "nats_cluster_id", "nats_client_id", "nats_channel", "server_port"} {
+		assert.Contains(t, err.Error(), name)
+	}
+}
+
+func TestRedacted_HidesPassword(t *testing.T) {
+	cfg := Default()
+
+	dump := cfg.Redacted()
+	assert.False(t, strings.Contains(dump, "order_password"))
+	assert.Contains(t, dump, "order_user")
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"host=db user=order password=s3cret dbname=orders", "host=db user=order password=xxxxx dbname=orders"},
+		{"host=db password='s3 \\'cret' dbname=orders", "host=db password=xxxxx dbname=orders"},
+		{"postgres://order:s3cret@db/orders", "postgres://order:xxxxx@db/orders"},
+		{"postgres://db/orders?password=s3cret&sslmode=disable", "postgres://db/orders?password=xxxxx&sslmode=disable"},
+		{"postgres://order:s3cret@db:port/orders", "<unparsable>"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, redactURL(tt.raw))
+	}
+
+	cfg := Default()
+	cfg.DatabaseURL = "host=db user=order password=s3cret dbname=orders"
+	assert.NotContains(t, cfg.Redacted(), "s3cret")
+}
+
+func TestLoad_Broker(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("BROKER", "jetstream")
+	t.Setenv("NATS_BACKOFF", "1s, 10s,1m")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "jetstream", cfg.Broker)
+	assert.Equal(t, []time.Duration{time.Second, 10 * time.Second, time.Minute}, cfg.NatsBackoff)
+
+	t.Setenv("NATS_BACKOFF", "1s,1s,1s,1s,1s,1s")
+	_, err = Load()
+	assert.ErrorContains(t, err, "nats_backoff")
+
+	t.Setenv("BROKER", "kafka")
+	t.Setenv("NATS_BACKOFF", "")
+	_, err = Load()
+	assert.ErrorContains(t, err, "broker")
+}
+
+func TestLoad_Batch(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("BATCH_SIZE", "10")
+	t.Setenv("BATCH_MAX_DELAY", "20ms")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.BatchSize)
+	assert.Equal(t, 20*time.Millisecond, cfg.BatchMaxDelay)
+
+	t.Setenv("BATCH_SIZE", "100")
+	_, err = Load()
+	assert.ErrorContains(t, err, "batch_size must not exceed nats_max_inflight")
+}
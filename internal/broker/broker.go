@@ -0,0 +1,101 @@
+// Package broker hides the message broker behind a small interface so that
+// the service and the publisher can run on NATS Streaming or NATS JetStream.
+package broker
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	KindSTAN      = "stan"
+	KindJetStream = "jetstream"
+)
+
+// Kinds lists the supported brokers.
+var Kinds = []string{KindSTAN, KindJetStream}
+
+// Message is one delivery of a published message.
+type Message interface {
+	Data() []byte
+	// Sequence is the position of the message in its channel or stream.
+	Sequence() uint64
+	// Timestamp is when the broker stored the message.
+	Timestamp() time.Time
+	// Redeliveries counts earlier deliveries of the same message.
+	Redeliveries() int
+	Ack() error
+	// Nack hands the message back for redelivery after AckWait or the
+	// matching Backoff step. Brokers without negative acks redeliver an
+	// unacknowledged message after AckWait anyway, and do nothing here.
+	Nack() error
+}
+
+type Handler func(msg Message)
+
+// SubscribeOptions configures a durable subscription in manual ack mode. A
+// message that is not acknowledged is redelivered after AckWait, or after
+// the matching Backoff step where the broker supports it.
+type SubscribeOptions struct {
+	Durable     string
+	AckWait     time.Duration
+	MaxInflight int
+	// MaxDeliver caps the number of deliveries of one message, 0 for no
+	// limit. NATS Streaming has no such limit and ignores it, as well as
+	// Backoff.
+	MaxDeliver int
+	Backoff    []time.Duration
+}
+
+type Consumer interface {
+	Subscribe(channel string, opts SubscribeOptions, h Handler) error
+	// Healthy reports whether the connection to the broker is up.
+	Healthy() error
+	// Close stops the subscriptions and closes the connection. Durable
+	// subscriptions survive, so a restarted consumer resumes where it stopped.
+	Close() error
+}
+
+type Publisher interface {
+	// PublishAsync sends data to channel and calls done with the broker's ID
+	// for the message once it is stored, or with the error. A non-empty msgID
+	// lets the broker drop repeated publishes of the same message: JetStream
+	// within the stream's duplicate window, Memory always. NATS Streaming
+	// cannot deduplicate and ignores it.
+	PublishAsync(channel, msgID string, data []byte, done func(id string, err error)) error
+	Close() error
+}
+
+// Broker is a connection that can both consume and publish.
+type Broker interface {
+	Consumer
+	Publisher
+}
+
+type Config struct {
+	Kind string
+	URL  string
+
+	// ClusterID and ClientID identify the NATS Streaming connection.
+	ClusterID string
+	ClientID  string
+
+	// Stream is the JetStream stream holding the channels. It is created on
+	// first use and extended with every channel published or subscribed to.
+	Stream string
+
+	// MaxPending limits publishes awaiting an ack, 0 for the client default.
+	MaxPending int
+}
+
+// Dial connects to the broker selected by cfg.Kind.
+func Dial(cfg Config) (Broker, error) {
+	switch cfg.Kind {
+	case KindSTAN:
+		return DialSTAN(cfg)
+	case KindJetStream:
+		return DialJetStream(cfg)
+	default:
+		return nil, fmt.Errorf("unknown broker %q", cfg.Kind)
+	}
+}
@@ -0,0 +1,148 @@
+package broker_test
+
+import (
+	"context"
+	"order-service/internal/broker"
+	"order-service/internal/broker/brokertest"
+	"testing"
+	"time"
+)
+
+func TestBrokers(t *testing.T) {
+	for _, kind := range broker.Kinds {
+		t.Run(kind, func(t *testing.T) {
+			cfg := brokertest.Run(t, kind)
+			opts := broker.SubscribeOptions{Durable: "test", AckWait: time.Second, MaxInflight: 1, MaxDeliver: 5}
+
+			b, received := subscribe(t, cfg, opts)
+			publish(t, b, "one")
+
+			msg := next(t, received)
+			if string(msg.Data()) != "one" || msg.Sequence() != 1 || msg.Redeliveries() != 0 {
+				t.Fatalf("got %q seq %d redeliveries %d", msg.Data(), msg.Sequence(), msg.Redeliveries())
+			}
+			if age := time.Since(msg.Timestamp()); age < 0 || age > time.Minute {
+				t.Errorf("unexpected timestamp %s", msg.Timestamp())
+			}
+
+			// A nacked message comes back after AckWait.
+			if err := msg.Nack(); err != nil {
+				t.Fatal(err)
+			}
+			msg = next(t, received)
+			if string(msg.Data()) != "one" || msg.Redeliveries() != 1 {
+				t.Fatalf("got %q redeliveries %d, want a redelivery of one", msg.Data(), msg.Redeliveries())
+			}
+			if err := msg.Ack(); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Healthy(); err != nil {
+				t.Errorf("Healthy: %v", err)
+			}
+			if err := b.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The durable resumes after the acknowledged message.
+			b, received = subscribe(t, cfg, opts)
+			publish(t, b, "two")
+			msg = next(t, received)
+			if string(msg.Data()) != "two" || msg.Sequence() != 2 {
+				t.Fatalf("got %q seq %d after resubscribing, want two seq 2", msg.Data(), msg.Sequence())
+			}
+			msg.Ack()
+			b.Close()
+		})
+	}
+}
+
+func TestJetStreamPending(t *testing.T) {
+	cfg := brokertest.Run(t, broker.KindJetStream)
+	b, received := subscribe(t, cfg, broker.SubscribeOptions{Durable: "test", AckWait: time.Minute, MaxInflight: 1})
+	defer b.Close()
+	js := b.(*broker.JetStream)
+
+	for _, data := range []string{"one", "two", "three"} {
+		publish(t, b, data)
+	}
+	msg := next(t, received)
+
+	pending, err := js.Pending(context.Background(), "test", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 3 {
+		t.Errorf("got %d pending with one message awaiting an ack, want 3", pending)
+	}
+
+	msg.Ack()
+	next(t, received).Ack()
+	next(t, received).Ack()
+	deadline := time.Now().Add(5 * time.Second)
+	for pending != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if pending, err = js.Pending(context.Background(), "test", "orders"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pending != 0 {
+		t.Errorf("got %d pending after acking everything, want 0", pending)
+	}
+
+	if _, err := js.Pending(context.Background(), "missing", "orders"); err == nil {
+		t.Error("expected an error for an unknown consumer")
+	}
+}
+
+func TestDialUnknownBroker(t *testing.T) {
+	if _, err := broker.Dial(broker.Config{Kind: "kafka"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func subscribe(t *testing.T, cfg broker.Config, opts broker.SubscribeOptions) (broker.Broker, chan broker.Message) {
+	t.Helper()
+	b, err := broker.Dial(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan broker.Message, 10)
+	if err := b.Subscribe("orders", opts, func(msg broker.Message) { received <- msg }); err != nil {
+		b.Close()
+		t.Fatal(err)
+	}
+	return b, received
+}
+
+func publish(t *testing.T, b broker.Publisher, data string) {
+	t.Helper()
+	acked := make(chan error, 1)
+	err := b.PublishAsync("orders", "", []byte(data), func(id string, err error) {
+		if err == nil && id == "" {
+			t.Error("ack without a message ID")
+		}
+		acked <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no publish ack")
+	}
+}
+
+func next(t *testing.T, received chan broker.Message) broker.Message {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(10 * time.Second):
+		t.Fatal("no message delivered")
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+// Package brokertest runs embedded NATS servers so that tests can exercise
+// every broker without external processes.
+package brokertest
+
+import (
+	"order-service/internal/broker"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	stanserver "github.com/nats-io/nats-streaming-server/server"
+)
+
+const ClusterID = "test-cluster"
+
+// Run starts an embedded server for kind, stopped when the test ends, and
+// returns the broker configuration to reach it.
+func Run(t testing.TB, kind string) broker.Config {
+	t.Helper()
+
+	cfg := broker.Config{Kind: kind, ClusterID: ClusterID, ClientID: "test-client", Stream: "ORDERS"}
+	switch kind {
+	case broker.KindSTAN:
+		cfg.URL = runSTAN(t)
+	case broker.KindJetStream:
+		cfg.URL = runJetStream(t)
+	default:
+		t.Fatalf("unknown broker %q", kind)
+	}
+	return cfg
+}
+
+func runJetStream(t testing.TB) string {
+	s, err := natsserver.NewServer(&natsserver.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("start nats-server: %v", err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		s.Shutdown()
+		t.Fatal("nats-server is not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s.ClientURL()
+}
+
+func runSTAN(t testing.TB) string {
+	opts := stanserver.GetDefaultOptions()
+	opts.ID = ClusterID
+	nopts := stanserver.DefaultNatsServerOptions
+	nopts.Host = "127.0.0.1"
+	nopts.Port = -1
+	nopts.NoLog = true
+	nopts.NoSigs = true
+
+	s, err := stanserver.RunServerWithOpts(opts, &nopts)
+	if err != nil {
+		t.Fatalf("start nats-streaming-server: %v", err)
+	}
+	t.Cleanup(s.Shutdown)
+	return s.ClientURL()
+}
@@ -0,0 +1,242 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// requestTimeout bounds JetStream API calls such as creating a consumer.
+const requestTimeout = 10 * time.Second
+
+// JetStream consumes from and publishes to subjects of one JetStream stream
+// using durable pull consumers with explicit acks.
+type JetStream struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	stream string
+
+	mu       sync.Mutex
+	subjects map[string]bool
+	subs     []jetstream.ConsumeContext
+}
+
+func DialJetStream(cfg Config) (*JetStream, error) {
+	if cfg.Stream == "" {
+		return nil, errors.New("no JetStream stream configured")
+	}
+	url := cfg.URL
+	if url == "" {
+		url = nats.DefaultURL
+	}
+
+	nc, err := nats.Connect(url,
+		nats.Name(cfg.ClientID),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			log.Printf("NATS connection lost: %v", err)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []jetstream.JetStreamOpt
+	if cfg.MaxPending > 0 {
+		opts = append(opts, jetstream.WithPublishAsyncMaxPending(cfg.MaxPending))
+	}
+	js, err := jetstream.New(nc, opts...)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &JetStream{nc: nc, js: js, stream: cfg.Stream, subjects: make(map[string]bool)}, nil
+}
+
+// Subscribe creates or updates the durable consumer for channel and starts
+// consuming it. The consumer is named after opts.Durable and the channel, so
+// one durable name can be used for several channels of the stream.
+func (b *JetStream) Subscribe(channel string, opts SubscribeOptions, h Handler) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	stream, err := b.ensureStream(ctx, channel)
+	if err != nil {
+		return err
+	}
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       consumerName(opts.Durable, channel),
+		FilterSubject: channel,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       opts.AckWait,
+		MaxDeliver:    opts.MaxDeliver,
+		BackOff:       opts.Backoff,
+		MaxAckPending: opts.MaxInflight,
+	})
+	if err != nil {
+		return fmt.Errorf("create consumer for %s: %w", channel, err)
+	}
+
+	sub, err := consumer.Consume(func(msg jetstream.Msg) {
+		h(newJetStreamMessage(msg, opts))
+	},
+		jetstream.PullMaxMessages(max(opts.MaxInflight, 1)),
+		jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			log.Printf("JetStream consumer for %s: %v", channel, err)
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *JetStream) PublishAsync(channel, msgID string, data []byte, done func(id string, err error)) error {
+	b.mu.Lock()
+	known := b.subjects[channel]
+	b.mu.Unlock()
+	if !known {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		_, err := b.ensureStream(ctx, channel)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	var opts []jetstream.PublishOpt
+	if msgID != "" {
+		opts = append(opts, jetstream.WithMsgID(msgID))
+	}
+	future, err := b.js.PublishAsync(channel, data, opts...)
+	if err != nil {
+		return err
+	}
+	go func() {
+		select {
+		case ack := <-future.Ok():
+			done(ack.Stream+":"+strconv.FormatUint(ack.Sequence, 10), nil)
+		case err := <-future.Err():
+			done("", err)
+		}
+	}()
+	return nil
+}
+
+// ensureStream returns the stream, creating it or adding subject to its
+// subjects when needed.
+func (b *JetStream) ensureStream(ctx context.Context, subject string) (jetstream.Stream, error) {
+	stream, err := b.js.Stream(ctx, b.stream)
+	switch {
+	case errors.Is(err, jetstream.ErrStreamNotFound):
+		stream, err = b.js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     b.stream,
+			Subjects: []string{subject},
+			Storage:  jetstream.FileStorage,
+		})
+		if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+			// Someone else created it meanwhile.
+			return b.ensureStream(ctx, subject)
+		}
+	case err == nil:
+		cfg := stream.CachedInfo().Config
+		if !slices.Contains(cfg.Subjects, subject) {
+			cfg.Subjects = append(cfg.Subjects, subject)
+			stream, err = b.js.UpdateStream(ctx, cfg)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stream %s: %w", b.stream, err)
+	}
+
+	b.mu.Lock()
+	b.subjects[subject] = true
+	b.mu.Unlock()
+	return stream, nil
+}
+
+// Pending returns how many messages of channel the consumer named after
+// durable has yet to get acked: those not delivered so far and those
+// awaiting an ack.
+func (b *JetStream) Pending(ctx context.Context, durable, channel string) (uint64, error) {
+	consumer, err := b.js.Consumer(ctx, b.stream, consumerName(durable, channel))
+	if err != nil {
+		return 0, err
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return info.NumPending + uint64(info.NumAckPending), nil
+}
+
+func (b *JetStream) Healthy() error {
+	if !b.nc.IsConnected() {
+		return fmt.Errorf("nats connection is %s", b.nc.Status())
+	}
+	return nil
+}
+
+// Close stops consuming and closes the connection. The durable consumers stay
+// on the server.
+func (b *JetStream) Close() error {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Stop()
+		<-sub.Closed()
+	}
+	b.nc.Close()
+	return nil
+}
+
+// consumerName derives a valid consumer name, which may not contain dots or
+// wildcards, from the durable name and the subject.
+func consumerName(durable, subject string) string {
+	return durable + "-" + strings.NewReplacer(".", "_", "*", "_", ">", "_").Replace(subject)
+}
+
+type jetStreamMessage struct {
+	msg  jetstream.Msg
+	meta jetstream.MsgMetadata
+	// delay is how long a nacked message waits, as the server would wait
+	// for an ack before redelivering it.
+	delay time.Duration
+}
+
+func newJetStreamMessage(msg jetstream.Msg, opts SubscribeOptions) jetStreamMessage {
+	m := jetStreamMessage{msg: msg, delay: opts.AckWait}
+	if meta, err := msg.Metadata(); err == nil {
+		m.meta = *meta
+	}
+	if len(opts.Backoff) > 0 {
+		m.delay = opts.Backoff[min(m.Redeliveries(), len(opts.Backoff)-1)]
+	}
+	return m
+}
+
+func (m jetStreamMessage) Data() []byte         { return m.msg.Data() }
+func (m jetStreamMessage) Sequence() uint64     { return m.meta.Sequence.Stream }
+func (m jetStreamMessage) Timestamp() time.Time { return m.meta.Timestamp }
+func (m jetStreamMessage) Ack() error           { return m.msg.Ack() }
+func (m jetStreamMessage) Nack() error          { return m.msg.NakWithDelay(m.delay) }
+
+func (m jetStreamMessage) Redeliveries() int {
+	return max(int(m.meta.NumDelivered)-1, 0)
+}
@@ -0,0 +1,288 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process broker for tests. Publishing only stores messages;
+// handlers run when Deliver is called, one message at a time on the caller's
+// goroutine, so tests decide exactly when and in which order messages are
+// handled. Unacknowledged messages are redelivered after Nack or ExpireAcks.
+//
+// Closing a Memory deactivates its subscriptions but keeps their durable
+// state, and subscribing again with the same durable name resumes it, which
+// stands in for a restarted consumer.
+type Memory struct {
+	mu       sync.Mutex
+	channels map[string]*memoryChannel
+	closed   bool
+}
+
+type memoryChannel struct {
+	records []*memoryRecord
+	subs    map[string]*memorySub
+	// msgIDs maps the message IDs seen on the channel to their sequences.
+	msgIDs map[string]uint64
+}
+
+type memoryRecord struct {
+	seq       uint64
+	data      []byte
+	timestamp time.Time
+}
+
+type memorySub struct {
+	opts    SubscribeOptions
+	handler Handler
+	active  bool
+
+	// next indexes the first record never delivered.
+	next int
+	// deliveries counts deliveries of every record not yet acknowledged.
+	deliveries map[uint64]int
+	// inflight holds records delivered and awaiting an ack or nack.
+	inflight map[uint64]bool
+	// redeliver holds sequences waiting for redelivery, lowest first.
+	redeliver []uint64
+}
+
+func NewMemory() *Memory {
+	return &Memory{channels: make(map[string]*memoryChannel)}
+}
+
+func (b *Memory) channel(name string) *memoryChannel {
+	ch, ok := b.channels[name]
+	if !ok {
+		ch = &memoryChannel{subs: make(map[string]*memorySub), msgIDs: make(map[string]uint64)}
+		b.channels[name] = ch
+	}
+	return ch
+}
+
+// Publish stores data on channel and returns its sequence.
+func (b *Memory) Publish(channel string, data []byte) uint64 {
+	return b.publish(channel, "", data)
+}
+
+func (b *Memory) publish(channel, msgID string, data []byte) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := b.channel(channel)
+	if seq, ok := ch.msgIDs[msgID]; ok && msgID != "" {
+		return seq
+	}
+	rec := &memoryRecord{
+		seq:       uint64(len(ch.records)) + 1,
+		data:      slices.Clone(data),
+		timestamp: time.Now(),
+	}
+	ch.records = append(ch.records, rec)
+	if msgID != "" {
+		ch.msgIDs[msgID] = rec.seq
+	}
+	return rec.seq
+}
+
+// PublishAsync stores data and calls done before returning. A repeated
+// msgID is acknowledged with the sequence of the first message without
+// storing anything.
+func (b *Memory) PublishAsync(channel, msgID string, data []byte, done func(id string, err error)) error {
+	seq := b.publish(channel, msgID, data)
+	done(channel+":"+strconv.FormatUint(seq, 10), nil)
+	return nil
+}
+
+func (b *Memory) Subscribe(channel string, opts SubscribeOptions, h Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := b.channel(channel)
+	sub, ok := ch.subs[opts.Durable]
+	switch {
+	case !ok:
+		sub = &memorySub{
+			deliveries: make(map[uint64]int),
+			inflight:   make(map[uint64]bool),
+		}
+		ch.subs[opts.Durable] = sub
+	case sub.active:
+		return fmt.Errorf("durable %q on %s is already subscribed", opts.Durable, channel)
+	}
+	sub.opts = opts
+	sub.handler = h
+	sub.active = true
+	b.closed = false
+	// Whatever the previous subscriber left unacknowledged comes back.
+	for seq := range sub.inflight {
+		sub.requeue(seq)
+	}
+	return nil
+}
+
+// Deliver hands every deliverable message to its handler, redeliveries first
+// and then new messages in sequence order, while respecting MaxInflight. It
+// returns once nothing more can be delivered and reports how many messages
+// were handled.
+func (b *Memory) Deliver() int {
+	delivered := 0
+	for {
+		sub, msg := b.nextDelivery()
+		if msg == nil {
+			return delivered
+		}
+		sub.handler(msg)
+		delivered++
+	}
+}
+
+func (b *Memory) nextDelivery() (*memorySub, *memoryMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.channels)) {
+		ch := b.channels[name]
+		for _, durable := range slices.Sorted(maps.Keys(ch.subs)) {
+			sub := ch.subs[durable]
+			if !sub.active || (sub.opts.MaxInflight > 0 && len(sub.inflight) >= sub.opts.MaxInflight) {
+				continue
+			}
+			if rec := sub.take(ch); rec != nil {
+				sub.deliveries[rec.seq]++
+				sub.inflight[rec.seq] = true
+				return sub, &memoryMessage{broker: b, sub: sub, record: rec, redeliveries: sub.deliveries[rec.seq] - 1}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// take removes the next record to deliver from the redelivery queue or,
+// when it is empty, from the new messages. Records that reached MaxDeliver
+// are dropped.
+func (s *memorySub) take(ch *memoryChannel) *memoryRecord {
+	for len(s.redeliver) > 0 {
+		seq := s.redeliver[0]
+		s.redeliver = s.redeliver[1:]
+		if s.opts.MaxDeliver > 0 && s.deliveries[seq] >= s.opts.MaxDeliver {
+			delete(s.deliveries, seq)
+			continue
+		}
+		return ch.records[seq-1]
+	}
+	if s.next < len(ch.records) {
+		s.next++
+		return ch.records[s.next-1]
+	}
+	return nil
+}
+
+func (s *memorySub) requeue(seq uint64) {
+	delete(s.inflight, seq)
+	i, found := slices.BinarySearch(s.redeliver, seq)
+	if !found {
+		s.redeliver = slices.Insert(s.redeliver, i, seq)
+	}
+}
+
+// ExpireAcks makes every message awaiting an ack due for redelivery, as if
+// AckWait had passed.
+func (b *Memory) ExpireAcks() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.channels {
+		for _, sub := range ch.subs {
+			for seq := range sub.inflight {
+				sub.requeue(seq)
+			}
+		}
+	}
+}
+
+// Unacked counts the messages on channel that some durable has received but
+// not acknowledged, including those waiting for redelivery.
+func (b *Memory) Unacked(channel string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	if ch, ok := b.channels[channel]; ok {
+		for _, sub := range ch.subs {
+			n += len(sub.deliveries)
+		}
+	}
+	return n
+}
+
+func (b *Memory) Healthy() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errors.New("closed")
+	}
+	return nil
+}
+
+func (b *Memory) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, ch := range b.channels {
+		for _, sub := range ch.subs {
+			sub.active = false
+			sub.handler = nil
+		}
+	}
+	return nil
+}
+
+type memoryMessage struct {
+	broker       *Memory
+	sub          *memorySub
+	record       *memoryRecord
+	redeliveries int
+}
+
+func (m *memoryMessage) Data() []byte         { return m.record.data }
+func (m *memoryMessage) Sequence() uint64     { return m.record.seq }
+func (m *memoryMessage) Timestamp() time.Time { return m.record.timestamp }
+func (m *memoryMessage) Redeliveries() int    { return m.redeliveries }
+
+func (m *memoryMessage) Ack() error {
+	m.broker.mu.Lock()
+	defer m.broker.mu.Unlock()
+
+	seq := m.record.seq
+	if _, ok := m.sub.deliveries[seq]; !ok {
+		return errors.New("message already acknowledged")
+	}
+	delete(m.sub.deliveries, seq)
+	delete(m.sub.inflight, seq)
+	if i, found := slices.BinarySearch(m.sub.redeliver, seq); found {
+		m.sub.redeliver = slices.Delete(m.sub.redeliver, i, i+1)
+	}
+	return nil
+}
+
+func (m *memoryMessage) Nack() error {
+	m.broker.mu.Lock()
+	defer m.broker.mu.Unlock()
+
+	if !m.sub.inflight[m.record.seq] {
+		return nil
+	}
+	m.sub.requeue(m.record.seq)
+	return nil
+}
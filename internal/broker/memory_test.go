@@ -0,0 +1,159 @@
+package broker_test
+
+import (
+	"order-service/internal/broker"
+	"slices"
+	"testing"
+)
+
+// record subscribes to channel and collects every delivery.
+func record(t *testing.T, b *broker.Memory, channel string, opts broker.SubscribeOptions) *[]broker.Message {
+	t.Helper()
+	var got []broker.Message
+	if err := b.Subscribe(channel, opts, func(msg broker.Message) { got = append(got, msg) }); err != nil {
+		t.Fatal(err)
+	}
+	return &got
+}
+
+func sequences(msgs []broker.Message) []uint64 {
+	seqs := make([]uint64, len(msgs))
+	for i, msg := range msgs {
+		seqs[i] = msg.Sequence()
+	}
+	return seqs
+}
+
+func TestMemory_MaxInflight(t *testing.T) {
+	b := broker.NewMemory()
+	for _, data := range []string{"a", "b", "c"} {
+		b.Publish("orders", []byte(data))
+	}
+	got := record(t, b, "orders", broker.SubscribeOptions{Durable: "test", MaxInflight: 2})
+
+	if n := b.Deliver(); n != 2 {
+		t.Fatalf("delivered %d, want 2 while nothing is acked", n)
+	}
+	(*got)[0].Ack()
+	if n := b.Deliver(); n != 1 {
+		t.Fatalf("delivered %d, want 1 after an ack", n)
+	}
+	if seqs := sequences(*got); !slices.Equal(seqs, []uint64{1, 2, 3}) {
+		t.Errorf("delivered %v", seqs)
+	}
+	if string((*got)[2].Data()) != "c" {
+		t.Errorf("got %q", (*got)[2].Data())
+	}
+	if n := b.Unacked("orders"); n != 2 {
+		t.Errorf("unacked %d, want 2", n)
+	}
+}
+
+func TestMemory_Redelivery(t *testing.T) {
+	b := broker.NewMemory()
+	b.Publish("orders", []byte("a"))
+	b.Publish("orders", []byte("b"))
+	got := record(t, b, "orders", broker.SubscribeOptions{Durable: "test", MaxInflight: 1})
+
+	b.Deliver()
+	(*got)[0].Nack()
+	b.Deliver()
+	// The nacked message is redelivered before the next new one.
+	if seqs := sequences(*got); !slices.Equal(seqs, []uint64{1, 1}) {
+		t.Fatalf("delivered %v, want [1 1]", seqs)
+	}
+	if r := (*got)[1].Redeliveries(); r != 1 {
+		t.Errorf("redeliveries %d, want 1", r)
+	}
+
+	// Without an ack or nack the message waits for ExpireAcks.
+	if n := b.Deliver(); n != 0 {
+		t.Fatalf("delivered %d before the ack expired", n)
+	}
+	b.ExpireAcks()
+	b.Deliver()
+	(*got)[2].Ack()
+	b.Deliver()
+	(*got)[3].Ack()
+	if seqs := sequences(*got); !slices.Equal(seqs, []uint64{1, 1, 1, 2}) {
+		t.Errorf("delivered %v, want [1 1 1 2]", seqs)
+	}
+	if n := b.Unacked("orders"); n != 0 {
+		t.Errorf("unacked %d, want 0", n)
+	}
+}
+
+func TestMemory_MaxDeliver(t *testing.T) {
+	b := broker.NewMemory()
+	b.Publish("orders", []byte("a"))
+	got := record(t, b, "orders", broker.SubscribeOptions{Durable: "test", MaxDeliver: 2})
+
+	for range 3 {
+		b.Deliver()
+		(*got)[len(*got)-1].Nack()
+	}
+	if len(*got) != 2 {
+		t.Errorf("delivered %d times, want MaxDeliver 2", len(*got))
+	}
+	if n := b.Unacked("orders"); n != 0 {
+		t.Errorf("unacked %d after giving up, want 0", n)
+	}
+}
+
+func TestMemory_DurableSurvivesClose(t *testing.T) {
+	b := broker.NewMemory()
+	b.Publish("orders", []byte("a"))
+	b.Publish("orders", []byte("b"))
+	opts := broker.SubscribeOptions{Durable: "test"}
+	got := record(t, b, "orders", opts)
+	b.Deliver()
+	(*got)[0].Ack()
+
+	if err := b.Subscribe("orders", opts, func(broker.Message) {}); err == nil {
+		t.Fatal("a durable can only be subscribed once")
+	}
+	b.Close()
+	if err := b.Healthy(); err == nil {
+		t.Error("closed broker reports healthy")
+	}
+	b.Publish("orders", []byte("c"))
+
+	got = record(t, b, "orders", opts)
+	b.Deliver()
+	// Unacked 2 is redelivered, then the new 3; acked 1 is not.
+	if seqs := sequences(*got); !slices.Equal(seqs, []uint64{2, 3}) {
+		t.Errorf("delivered %v after resubscribing, want [2 3]", seqs)
+	}
+
+	// Other durables start from the beginning of the channel.
+	other := record(t, b, "orders", broker.SubscribeOptions{Durable: "other"})
+	b.Deliver()
+	if len(*other) != 3 {
+		t.Errorf("new durable got %d messages, want 3", len(*other))
+	}
+}
+
+func TestMemory_DropsRepeatedMsgID(t *testing.T) {
+	b := broker.NewMemory()
+	var ids []string
+	for _, msgID := range []string{"order.accepted:a", "order.accepted:a", ""} {
+		err := b.PublishAsync("events", msgID, []byte("a"), func(id string, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(ids, []string{"events:1", "events:1", "events:2"}) {
+		t.Errorf("acked %v, want the repeat acked as the first message", ids)
+	}
+
+	got := record(t, b, "events", broker.SubscribeOptions{Durable: "test"})
+	b.Deliver()
+	if seqs := sequences(*got); !slices.Equal(seqs, []uint64{1, 2}) {
+		t.Errorf("delivered %v, want [1 2]", seqs)
+	}
+}
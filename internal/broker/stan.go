@@ -0,0 +1,112 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/stan.go"
+)
+
+// STAN consumes from and publishes to NATS Streaming channels.
+type STAN struct {
+	conn stan.Conn
+
+	mu       sync.Mutex
+	subs     []stan.Subscription
+	connLost error
+}
+
+func DialSTAN(cfg Config) (*STAN, error) {
+	b := &STAN{}
+	opts := []stan.Option{stan.SetConnectionLostHandler(b.onConnectionLost)}
+	if cfg.URL != "" {
+		opts = append(opts, stan.NatsURL(cfg.URL))
+	}
+	if cfg.MaxPending > 0 {
+		opts = append(opts, stan.MaxPubAcksInflight(cfg.MaxPending))
+	}
+
+	conn, err := stan.Connect(cfg.ClusterID, cfg.ClientID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	b.conn = conn
+	return b, nil
+}
+
+func (b *STAN) Subscribe(channel string, opts SubscribeOptions, h Handler) error {
+	sub, err := b.conn.Subscribe(channel, func(msg *stan.Msg) {
+		h(stanMessage{msg})
+	},
+		stan.DurableName(opts.Durable),
+		stan.SetManualAckMode(),
+		stan.AckWait(opts.AckWait),
+		stan.MaxInflight(opts.MaxInflight),
+	)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *STAN) PublishAsync(channel, _ string, data []byte, done func(id string, err error)) error {
+	_, err := b.conn.PublishAsync(channel, data, done)
+	return err
+}
+
+func (b *STAN) onConnectionLost(_ stan.Conn, reason error) {
+	log.Printf("NATS connection lost: %v", reason)
+	b.mu.Lock()
+	b.connLost = reason
+	b.mu.Unlock()
+}
+
+func (b *STAN) Healthy() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.connLost != nil:
+		return fmt.Errorf("connection lost: %w", b.connLost)
+	case !b.conn.NatsConn().IsConnected():
+		return fmt.Errorf("nats connection is %s", b.conn.NatsConn().Status())
+	}
+	return nil
+}
+
+// Close closes the subscriptions rather than unsubscribing, which would
+// delete the durables.
+func (b *STAN) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	for _, sub := range b.subs {
+		if err := sub.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close subscription: %w", err))
+		}
+	}
+	b.subs = nil
+	if err := b.conn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close connection: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+type stanMessage struct {
+	msg *stan.Msg
+}
+
+func (m stanMessage) Data() []byte         { return m.msg.Data }
+func (m stanMessage) Sequence() uint64     { return m.msg.Sequence }
+func (m stanMessage) Timestamp() time.Time { return time.Unix(0, m.msg.Timestamp) }
+func (m stanMessage) Redeliveries() int    { return int(m.msg.RedeliveryCount) }
+func (m stanMessage) Ack() error           { return m.msg.Ack() }
+func (m stanMessage) Nack() error          { return nil }
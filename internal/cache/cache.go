@@ -1,57 +1,333 @@
 package cache
 
 import (
+	"container/list"
+	"context"
+	"errors"
 	"order-service/internal/model"
 	"sync"
+	"time"
 )
 
+var ErrNotFound = errors.New("order not found")
+
+// loadTimeout bounds a load shared by concurrent misses.
+const loadTimeout = 5 * time.Second
+
+// Loader fetches an order from the backing store on a cache miss.
+type Loader interface {
+	GetOrderByUID(ctx context.Context, orderUID string) (*model.Order, error)
+}
+
+type Options struct {
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+	Loader     Loader
+}
+
+type call struct {
+	done  chan struct{}
+	order *model.Order
+	err   error
+}
+
+type entry struct {
+	order     *model.Order
+	size      int64
+	expiresAt time.Time
+}
+
 type Cache struct {
-	mu     sync.RWMutex
-	orders map[string]*model.Order
+	mu        sync.Mutex
+	opts      Options
+	orders    map[string]*list.Element
+	indexes   indexes
+	lru       *list.List
+	bytes     int64
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	now       func() time.Time
+
+	loadMu  sync.Mutex
+	loading map[string]*call
 }
 
 func New() *Cache {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a cache bounded by opts. Zero limits mean unbounded
+// and a zero TTL keeps entries until they are evicted.
+func NewWithOptions(opts Options) *Cache {
 	return &Cache{
-		orders: make(map[string]*model.Order),
+		opts:    opts,
+		orders:  make(map[string]*list.Element),
+		indexes: newIndexes(),
+		lru:     list.New(),
+		now:     time.Now,
+		loading: make(map[string]*call),
 	}
 }
 
 func (c *Cache) Set(order *model.Order) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.orders[order.OrderUID] = order
+	c.set(order)
 }
 
 func (c *Cache) Get(orderUID string) (*model.Order, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	order, exists := c.orders[orderUID]
-	return order, exists
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.orders[orderUID]
+	if !exists {
+		c.misses++
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if c.expired(e) {
+		c.remove(elem)
+		c.misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.hits++
+	return e.order, true
+}
+
+// GetOrLoad returns the cached order or, on a miss, reads it through the
+// configured Loader and stores the result. Concurrent misses for the same
+// UID share a single load. The load is detached from ctx and bounded by
+// loadTimeout, so a caller giving up does not fail it for the others; each
+// caller stops waiting when its own ctx is done.
+func (c *Cache) GetOrLoad(ctx context.Context, orderUID string) (*model.Order, error) {
+	if order, exists := c.Get(orderUID); exists {
+		return order, nil
+	}
+	if c.opts.Loader == nil {
+		return nil, ErrNotFound
+	}
+
+	c.loadMu.Lock()
+	cl, exists := c.loading[orderUID]
+	if !exists {
+		cl = &call{done: make(chan struct{})}
+		c.loading[orderUID] = cl
+		go c.load(context.WithoutCancel(ctx), orderUID, cl)
+	}
+	c.loadMu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.order, cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Cache) load(ctx context.Context, orderUID string, cl *call) {
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+
+	cl.order, cl.err = c.opts.Loader.GetOrderByUID(ctx, orderUID)
+	if cl.err == nil {
+		c.add(cl.order)
+	}
+
+	c.loadMu.Lock()
+	delete(c.loading, orderUID)
+	c.loadMu.Unlock()
+	close(cl.done)
 }
 
 func (c *Cache) GetAll() []*model.Order {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	orders := make([]*model.Order, 0, len(c.orders))
-	for _, order := range c.orders {
-		orders = append(orders, order)
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry)
+		if c.expired(e) {
+			c.remove(elem)
+		} else {
+			orders = append(orders, e.order)
+		}
+		elem = next
 	}
 	return orders
 }
 
+// Restore replaces the cache contents. When orders exceed the configured
+// limits only the last ones in the slice are kept.
 func (c *Cache) Restore(orders []*model.Order) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
-	c.orders = make(map[string]*model.Order)
+
+	c.orders = make(map[string]*list.Element)
+	c.indexes = newIndexes()
+	c.lru.Init()
+	c.bytes = 0
 	for _, order := range orders {
-		c.orders[order.OrderUID] = order
+		c.set(order)
 	}
 }
 
+// Warm appends orders behind the existing entries without evicting anything,
+// so callers can load the most relevant orders first. It reports whether the
+// cache still has room after the batch.
+func (c *Cache) Warm(orders []*model.Order) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, order := range orders {
+		if _, exists := c.orders[order.OrderUID]; exists {
+			continue
+		}
+		if c.full() {
+			return false
+		}
+		e := &entry{order: order, size: orderSize(order)}
+		if c.opts.TTL > 0 {
+			e.expiresAt = c.now().Add(c.opts.TTL)
+		}
+		c.orders[order.OrderUID] = c.lru.PushBack(e)
+		c.indexes.add(order)
+		c.bytes += e.size
+	}
+	return !c.full()
+}
+
 func (c *Cache) Size() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return len(c.orders)
-}
\ No newline at end of file
+}
+
+func (c *Cache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+type Stats struct {
+	Size      int
+	Bytes     int64
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Size:      len(c.orders),
+		Bytes:     c.bytes,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+func (c *Cache) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+// add stores a loaded order unless its UID was cached while it was loading:
+// that entry came from the subscriber and is at least as new.
+func (c *Cache) add(order *model.Order) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.orders[order.OrderUID]; exists && !c.expired(elem.Value.(*entry)) {
+		return
+	}
+	c.set(order)
+}
+
+func (c *Cache) set(order *model.Order) {
+	e := &entry{order: order, size: orderSize(order)}
+	if c.opts.TTL > 0 {
+		e.expiresAt = c.now().Add(c.opts.TTL)
+	}
+
+	if elem, exists := c.orders[order.OrderUID]; exists {
+		old := elem.Value.(*entry)
+		c.bytes -= old.size
+		c.indexes.delete(old.order)
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+	} else {
+		c.orders[order.OrderUID] = c.lru.PushFront(e)
+	}
+	c.indexes.add(order)
+	c.bytes += e.size
+
+	c.evict()
+}
+
+func (c *Cache) evict() {
+	for c.lru.Len() > 1 && c.overLimit() {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+}
+
+func (c *Cache) overLimit() bool {
+	if c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		return true
+	}
+	return c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes
+}
+
+func (c *Cache) full() bool {
+	if c.opts.MaxEntries > 0 && c.lru.Len() >= c.opts.MaxEntries {
+		return true
+	}
+	return c.opts.MaxBytes > 0 && c.bytes >= c.opts.MaxBytes
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*entry)
+	delete(c.orders, e.order.OrderUID)
+	c.indexes.delete(e.order)
+	c.bytes -= e.size
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return !e.expiresAt.IsZero() && c.now().After(e.expiresAt)
+}
+
+// orderSize approximates the memory held by an order: fixed struct overhead
+// plus the length of every string field.
+func orderSize(o *model.Order) int64 {
+	const (
+		orderOverhead = 256
+		itemOverhead  = 160
+	)
+
+	size := int64(orderOverhead)
+	size += int64(len(o.OrderUID) + len(o.TrackNumber) + len(o.Entry) + len(o.Locale) +
+		len(o.InternalSignature) + len(o.CustomerID) + len(o.DeliveryService) +
+		len(o.Shardkey) + len(o.OofShard))
+
+	d := o.Delivery
+	size += int64(len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) +
+		len(d.Address) + len(d.Region) + len(d.Email))
+
+	p := o.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) +
+		len(p.Provider) + len(p.Bank))
+
+	for _, item := range o.Items {
+		size += itemOverhead
+		size += int64(len(item.TrackNumber) + len(item.Rid) + len(item.Name) +
+			len(item.Size) + len(item.Brand))
+	}
+	return size
+}
@@ -1,7 +1,11 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"order-service/internal/model"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -16,7 +20,7 @@ func TestCacheOperations(t *testing.T) {
 	}
 
 	cache.Set(order)
-	
+
 	retrieved, exists := cache.Get("test123")
 	if !exists {
 		t.Error("Expected order to exist in cache")
@@ -35,3 +39,335 @@ func TestCacheOperations(t *testing.T) {
 		t.Errorf("Expected cache size 1, got %d", size)
 	}
 }
+
+type stubLoader struct {
+	orders map[string]*model.Order
+	calls  int
+}
+
+func (l *stubLoader) GetOrderByUID(ctx context.Context, orderUID string) (*model.Order, error) {
+	l.calls++
+	order, ok := l.orders[orderUID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return order, nil
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	cache := NewWithOptions(Options{MaxEntries: 2})
+
+	cache.Set(&model.Order{OrderUID: "a"})
+	cache.Set(&model.Order{OrderUID: "b"})
+	cache.Get("a")
+	cache.Set(&model.Order{OrderUID: "c"})
+
+	if _, exists := cache.Get("b"); exists {
+		t.Error("Expected least recently used order to be evicted")
+	}
+	if _, exists := cache.Get("a"); !exists {
+		t.Error("Expected recently used order to stay in cache")
+	}
+	if size := cache.Size(); size != 2 {
+		t.Errorf("Expected cache size 2, got %d", size)
+	}
+	if evictions := cache.Evictions(); evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", evictions)
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	one := &model.Order{OrderUID: "a"}
+	cache := NewWithOptions(Options{MaxBytes: orderSize(one) * 2})
+
+	cache.Set(one)
+	cache.Set(&model.Order{OrderUID: "b"})
+	cache.Set(&model.Order{OrderUID: "c"})
+
+	if size := cache.Size(); size != 2 {
+		t.Errorf("Expected cache size 2, got %d", size)
+	}
+	if cache.Bytes() > orderSize(one)*2 {
+		t.Errorf("Expected cache bytes within limit, got %d", cache.Bytes())
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Now()
+	cache := NewWithOptions(Options{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	cache.Set(&model.Order{OrderUID: "a"})
+	if _, exists := cache.Get("a"); !exists {
+		t.Error("Expected order to exist before TTL")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, exists := cache.Get("a"); exists {
+		t.Error("Expected order to expire after TTL")
+	}
+	if size := cache.Size(); size != 0 {
+		t.Errorf("Expected cache size 0, got %d", size)
+	}
+}
+
+func TestCacheRestoreRespectsLimit(t *testing.T) {
+	cache := NewWithOptions(Options{MaxEntries: 2})
+
+	cache.Restore([]*model.Order{{OrderUID: "a"}, {OrderUID: "b"}, {OrderUID: "c"}})
+
+	if size := cache.Size(); size != 2 {
+		t.Errorf("Expected cache size 2, got %d", size)
+	}
+	if _, exists := cache.Get("c"); !exists {
+		t.Error("Expected last restored order to be cached")
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	loader := &stubLoader{orders: map[string]*model.Order{"db1": {OrderUID: "db1"}}}
+	cache := NewWithOptions(Options{Loader: loader})
+
+	order, err := cache.GetOrLoad(context.Background(), "db1")
+	if err != nil {
+		t.Fatalf("Expected order to be loaded, got %v", err)
+	}
+	if order.OrderUID != "db1" {
+		t.Errorf("Expected order UID 'db1', got '%s'", order.OrderUID)
+	}
+
+	cache.GetOrLoad(context.Background(), "db1")
+	if loader.calls != 1 {
+		t.Errorf("Expected loader to be called once, got %d", loader.calls)
+	}
+
+	if _, err := cache.GetOrLoad(context.Background(), "missing"); err == nil {
+		t.Error("Expected error for missing order")
+	}
+}
+
+func TestCacheGetOrLoad_NoLoader(t *testing.T) {
+	cache := New()
+
+	if _, err := cache.GetOrLoad(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+type blockingLoader struct {
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (l *blockingLoader) GetOrderByUID(ctx context.Context, orderUID string) (*model.Order, error) {
+	l.calls.Add(1)
+	<-l.release
+	return &model.Order{OrderUID: orderUID}, nil
+}
+
+func TestCacheGetOrLoad_CoalescesMisses(t *testing.T) {
+	loader := &blockingLoader{release: make(chan struct{})}
+	cache := NewWithOptions(Options{Loader: loader})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.GetOrLoad(context.Background(), "hot"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+
+	for loader.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(loader.release)
+	wg.Wait()
+
+	if calls := loader.calls.Load(); calls != 1 {
+		t.Errorf("Expected a single load, got %d", calls)
+	}
+}
+
+func TestCacheGetOrLoad_CallerCancelDoesNotFailLoad(t *testing.T) {
+	loader := &blockingLoader{release: make(chan struct{})}
+	cache := NewWithOptions(Options{Loader: loader})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cache.GetOrLoad(ctx, "hot")
+		errs <- err
+	}()
+	for loader.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := cache.GetOrLoad(context.Background(), "hot")
+		waiter <- err
+	}()
+
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled caller to stop waiting, got %v", err)
+	}
+	close(loader.release)
+	if err := <-waiter; err != nil {
+		t.Errorf("Expected the other caller to get the order, got %v", err)
+	}
+	if _, exists := cache.Get("hot"); !exists {
+		t.Error("Expected the loaded order to be cached")
+	}
+}
+
+func TestCacheGetOrLoad_KeepsNewerEntry(t *testing.T) {
+	loader := &blockingLoader{release: make(chan struct{})}
+	cache := NewWithOptions(Options{Loader: loader})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.GetOrLoad(context.Background(), "hot")
+	}()
+	for loader.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	fresh := &model.Order{OrderUID: "hot", TrackNumber: "FRESH"}
+	cache.Set(fresh)
+	close(loader.release)
+	<-done
+
+	if order, _ := cache.Get("hot"); order != fresh {
+		t.Error("Expected the order set during the load to stay cached")
+	}
+}
+
+func TestCacheWarm(t *testing.T) {
+	cache := NewWithOptions(Options{MaxEntries: 3})
+
+	if !cache.Warm([]*model.Order{{OrderUID: "newest"}, {OrderUID: "newer"}}) {
+		t.Error("Expected cache to have room after first page")
+	}
+	if cache.Warm([]*model.Order{{OrderUID: "old"}, {OrderUID: "oldest"}}) {
+		t.Error("Expected cache to be full after second page")
+	}
+	if size := cache.Size(); size != 3 {
+		t.Errorf("Expected cache size 3, got %d", size)
+	}
+
+	cache.Set(&model.Order{OrderUID: "incoming"})
+	if _, exists := cache.Get("newest"); !exists {
+		t.Error("Expected newest warmed order to survive eviction")
+	}
+	if _, exists := cache.Get("old"); exists {
+		t.Error("Expected oldest warmed order to be evicted first")
+	}
+}
+
+func TestCacheSecondaryIndexes(t *testing.T) {
+	cache := NewWithOptions(Options{MaxEntries: 2})
+	base := time.Date(2021, 11, 26, 0, 0, 0, 0, time.UTC)
+
+	cache.Set(&model.Order{OrderUID: "a", TrackNumber: "T1", CustomerID: "c1", DateCreated: base,
+		Payment: model.Payment{Transaction: "a"}})
+	cache.Set(&model.Order{OrderUID: "b", TrackNumber: "T1", CustomerID: "c1", DateCreated: base.Add(time.Hour),
+		Payment: model.Payment{Transaction: "b"}})
+
+	if orders := cache.ByCustomer("c1"); len(orders) != 2 || orders[0].OrderUID != "b" {
+		t.Errorf("Expected orders [b a] for customer c1, got %v", uids(orders))
+	}
+	if orders := cache.ByTransaction("a"); len(orders) != 1 || orders[0].OrderUID != "a" {
+		t.Errorf("Expected order a for transaction a, got %v", uids(orders))
+	}
+
+	// Replacing an order moves it between index keys.
+	cache.Set(&model.Order{OrderUID: "b", TrackNumber: "T2", CustomerID: "c2", DateCreated: base.Add(time.Hour),
+		Payment: model.Payment{Transaction: "b"}})
+	if orders := cache.ByTrackNumber("T1"); len(orders) != 1 || orders[0].OrderUID != "a" {
+		t.Errorf("Expected only order a under T1 after update, got %v", uids(orders))
+	}
+	if orders := cache.ByCustomer("c2"); len(orders) != 1 {
+		t.Errorf("Expected order b under c2 after update, got %v", uids(orders))
+	}
+
+	// Eviction drops the least recently used order a from every index.
+	cache.Set(&model.Order{OrderUID: "c", TrackNumber: "T3", CustomerID: "c3", DateCreated: base})
+	if orders := cache.ByTrackNumber("T1"); len(orders) != 0 {
+		t.Errorf("Expected evicted order to leave the track index, got %v", uids(orders))
+	}
+	if orders := cache.ByTransaction("a"); len(orders) != 0 {
+		t.Errorf("Expected evicted order to leave the transaction index, got %v", uids(orders))
+	}
+
+	cache.Restore(nil)
+	if orders := cache.ByCustomer("c2"); len(orders) != 0 {
+		t.Errorf("Expected Restore to reset indexes, got %v", uids(orders))
+	}
+
+	cache.Warm([]*model.Order{{OrderUID: "d", CustomerID: "c4"}})
+	if orders := cache.ByCustomer("c4"); len(orders) != 1 {
+		t.Errorf("Expected warmed order in the customer index, got %v", uids(orders))
+	}
+}
+
+func TestCacheIndexSkipsExpired(t *testing.T) {
+	now := time.Now()
+	cache := NewWithOptions(Options{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+
+	cache.Set(&model.Order{OrderUID: "a", CustomerID: "c1"})
+	now = now.Add(2 * time.Minute)
+
+	if orders := cache.ByCustomer("c1"); len(orders) != 0 {
+		t.Errorf("Expected expired order to be skipped, got %v", uids(orders))
+	}
+	if size := cache.Size(); size != 0 {
+		t.Errorf("Expected expired order to be removed, size is %d", size)
+	}
+}
+
+func TestCacheIndexSkipsStaleUIDs(t *testing.T) {
+	cache := New()
+	cache.Set(&model.Order{OrderUID: "a", TrackNumber: "T1"})
+	cache.indexes.track.add("T1", "gone")
+
+	if orders := cache.ByTrackNumber("T1"); len(orders) != 1 || orders[0].OrderUID != "a" {
+		t.Errorf("Expected only the cached order, got %v", uids(orders))
+	}
+}
+
+func TestCacheIndexDuringRestore(t *testing.T) {
+	cache := New()
+	orders := []*model.Order{{OrderUID: "a", TrackNumber: "T1"}, {OrderUID: "b", TrackNumber: "T1"}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cache.Restore(orders)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		cache.ByTrackNumber("T1")
+	}
+	wg.Wait()
+
+	if found := cache.ByTrackNumber("T1"); len(found) != 2 {
+		t.Errorf("Expected both restored orders, got %v", uids(found))
+	}
+}
+
+func uids(orders []*model.Order) []string {
+	out := make([]string, len(orders))
+	for i, o := range orders {
+		out[i] = o.OrderUID
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"container/list"
+	"order-service/internal/model"
+	"slices"
+	"strings"
+)
+
+// index maps a secondary key to the UIDs of the cached orders carrying it.
+type index map[string]map[string]struct{}
+
+func (ix index) add(key, orderUID string) {
+	if key == "" {
+		return
+	}
+	uids, exists := ix[key]
+	if !exists {
+		uids = make(map[string]struct{})
+		ix[key] = uids
+	}
+	uids[orderUID] = struct{}{}
+}
+
+func (ix index) delete(key, orderUID string) {
+	uids, exists := ix[key]
+	if !exists {
+		return
+	}
+	delete(uids, orderUID)
+	if len(uids) == 0 {
+		delete(ix, key)
+	}
+}
+
+type indexes struct {
+	track       index
+	customer    index
+	transaction index
+}
+
+func newIndexes() indexes {
+	return indexes{
+		track:       make(index),
+		customer:    make(index),
+		transaction: make(index),
+	}
+}
+
+func (ix indexes) add(o *model.Order) {
+	ix.track.add(o.TrackNumber, o.OrderUID)
+	ix.customer.add(o.CustomerID, o.OrderUID)
+	ix.transaction.add(o.Payment.Transaction, o.OrderUID)
+}
+
+func (ix indexes) delete(o *model.Order) {
+	ix.track.delete(o.TrackNumber, o.OrderUID)
+	ix.customer.delete(o.CustomerID, o.OrderUID)
+	ix.transaction.delete(o.Payment.Transaction, o.OrderUID)
+}
+
+// ByTrackNumber returns the cached orders with the given track number,
+// newest first.
+func (c *Cache) ByTrackNumber(trackNumber string) []*model.Order {
+	return c.lookup(func(ix indexes) index { return ix.track }, trackNumber)
+}
+
+// ByCustomer returns the cached orders of a customer, newest first.
+func (c *Cache) ByCustomer(customerID string) []*model.Order {
+	return c.lookup(func(ix indexes) index { return ix.customer }, customerID)
+}
+
+// ByTransaction returns the cached orders paid by the given transaction,
+// newest first.
+func (c *Cache) ByTransaction(transaction string) []*model.Order {
+	return c.lookup(func(ix indexes) index { return ix.transaction }, transaction)
+}
+
+// lookup reads the index chosen by which under the lock, since Restore
+// replaces the indexes.
+func (c *Cache) lookup(which func(indexes) index, key string) []*model.Order {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expired []*list.Element
+	orders := []*model.Order{}
+	for uid := range which(c.indexes)[key] {
+		elem, exists := c.orders[uid]
+		if !exists {
+			continue
+		}
+		e := elem.Value.(*entry)
+		if c.expired(e) {
+			expired = append(expired, elem)
+			continue
+		}
+		c.lru.MoveToFront(elem)
+		orders = append(orders, e.order)
+	}
+	for _, elem := range expired {
+		c.remove(elem)
+	}
+
+	slices.SortFunc(orders, func(a, b *model.Order) int {
+		if n := b.DateCreated.Compare(a.DateCreated); n != 0 {
+			return n
+		}
+		return strings.Compare(b.OrderUID, a.OrderUID)
+	})
+	return orders
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"order-service/internal/repository"
+	"strconv"
+)
+
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+)
+
+// ReplayFunc runs a raw message of the given dead letter kind back through
+// the pipeline that handles that kind.
+type ReplayFunc func(ctx context.Context, kind string, data []byte) error
+
+// StageFunc reports the pipeline stage that produced a replay error.
+type StageFunc func(err error) string
+
+type DeadLetterHandler struct {
+	store  repository.DeadLetterStore
+	replay ReplayFunc
+	stage  StageFunc
+}
+
+func NewDeadLetterHandler(store repository.DeadLetterStore, replay ReplayFunc, stage StageFunc) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		store:  store,
+		replay: replay,
+		stage:  stage,
+	}
+}
+
+func (h *DeadLetterHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.DeadLetterFilter{
+		Kind:  query.Get("kind"),
+		Stage: query.Get("stage"),
+		Limit: defaultDeadLetterLimit,
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = min(limit, maxDeadLetterLimit)
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || before <= 0 {
+			writeError(w, http.StatusBadRequest, "before must be a positive integer")
+			return
+		}
+		filter.BeforeID = before
+	}
+
+	deadLetters, err := h.store.ListDeadLetters(r.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to list dead letters: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list dead letters")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"dead_letters": deadLetters,
+	})
+}
+
+func (h *DeadLetterHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	dl, err := h.store.GetDeadLetter(r.Context(), id)
+	if errors.Is(err, repository.ErrDeadLetterNotFound) {
+		writeError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load dead letter %d: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "Failed to load dead letter")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dl)
+}
+
+// Replay pushes a dead letter back through the pipeline of its kind: orders
+// are ingested again, status and refund events applied again. A non-empty
+// request body replaces the stored payload, so a corrected message can be
+// replayed in place of the original.
+func (h *DeadLetterHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	id, ok := deadLetterID(w, r)
+	if !ok {
+		return
+	}
+
+	dl, err := h.store.GetDeadLetter(r.Context(), id)
+	if errors.Is(err, repository.ErrDeadLetterNotFound) {
+		writeError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load dead letter %d: %v", id, err)
+		writeError(w, http.StatusInternalServerError, "Failed to load dead letter")
+		return
+	}
+
+	payload := []byte(dl.Payload)
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	if len(body) > 0 {
+		payload = body
+	}
+
+	if err := h.replay(r.Context(), dl.Kind, payload); err != nil {
+		stage := h.stage(err)
+		if updateErr := h.store.UpdateDeadLetterError(r.Context(), id, stage, err.Error()); updateErr != nil {
+			log.Printf("Failed to update dead letter %d: %v", id, updateErr)
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"id":    id,
+			"stage": stage,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.store.MarkDeadLetterReplayed(r.Context(), id); err != nil {
+		log.Printf("Failed to mark dead letter %d replayed: %v", id, err)
+	}
+	log.Printf("Dead letter %d replayed successfully", id)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":     id,
+		"status": "replayed",
+	})
+}
+
+func deadLetterID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Invalid dead letter id")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
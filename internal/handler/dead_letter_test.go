@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeadLetterStore keeps dead letters by ID and records the last filter.
+type fakeDeadLetterStore struct {
+	deadLetters map[int64]*model.DeadLetter
+	filter      repository.DeadLetterFilter
+}
+
+func (f *fakeDeadLetterStore) SaveDeadLetter(ctx context.Context, dl *model.DeadLetter) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeDeadLetterStore) ListDeadLetters(ctx context.Context, filter repository.DeadLetterFilter) ([]*model.DeadLetter, error) {
+	f.filter = filter
+	return []*model.DeadLetter{}, nil
+}
+
+func (f *fakeDeadLetterStore) GetDeadLetter(ctx context.Context, id int64) (*model.DeadLetter, error) {
+	dl, ok := f.deadLetters[id]
+	if !ok {
+		return nil, repository.ErrDeadLetterNotFound
+	}
+	return dl, nil
+}
+
+func (f *fakeDeadLetterStore) MarkDeadLetterReplayed(ctx context.Context, id int64) error {
+	now := time.Now()
+	f.deadLetters[id].ReplayedAt = &now
+	return nil
+}
+
+func (f *fakeDeadLetterStore) UpdateDeadLetterError(ctx context.Context, id int64, stage, errText string) error {
+	f.deadLetters[id].Stage, f.deadLetters[id].Error = stage, errText
+	return nil
+}
+
+func newDeadLetterMux(store *fakeDeadLetterStore, replay ReplayFunc) *http.ServeMux {
+	h := NewDeadLetterHandler(store, replay, func(err error) string { return model.StageValidate })
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dead-letters", h.List)
+	mux.HandleFunc("GET /dead-letters/{id}", h.Get)
+	mux.HandleFunc("POST /dead-letters/{id}/replay", h.Replay)
+	return mux
+}
+
+func TestDeadLetterHandler_List(t *testing.T) {
+	tests := []struct {
+		query  string
+		code   int
+		filter repository.DeadLetterFilter
+	}{
+		{"", http.StatusOK, repository.DeadLetterFilter{Limit: 50}},
+		{"?kind=refund&stage=persist&limit=10&before=42", http.StatusOK, repository.DeadLetterFilter{Kind: "refund", Stage: "persist", Limit: 10, BeforeID: 42}},
+		{"?limit=100000", http.StatusOK, repository.DeadLetterFilter{Limit: 500}},
+		{"?limit=0", http.StatusBadRequest, repository.DeadLetterFilter{}},
+		{"?limit=ten", http.StatusBadRequest, repository.DeadLetterFilter{}},
+		{"?before=-1", http.StatusBadRequest, repository.DeadLetterFilter{}},
+		{"?before=abc", http.StatusBadRequest, repository.DeadLetterFilter{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			store := &fakeDeadLetterStore{}
+			rec := httptest.NewRecorder()
+			newDeadLetterMux(store, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dead-letters"+tt.query, nil))
+
+			require.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, tt.filter, store.filter)
+		})
+	}
+}
+
+func TestDeadLetterHandler_Get(t *testing.T) {
+	store := &fakeDeadLetterStore{deadLetters: map[int64]*model.DeadLetter{7: {ID: 7, Kind: model.DeadLetterOrder}}}
+	mux := newDeadLetterMux(store, nil)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dead-letters/7", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var dl model.DeadLetter
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&dl))
+	assert.Equal(t, int64(7), dl.ID)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dead-letters/8", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dead-letters/x", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDeadLetterHandler_Replay(t *testing.T) {
+	store := &fakeDeadLetterStore{deadLetters: map[int64]*model.DeadLetter{
+		1: {ID: 1, Kind: model.DeadLetterStatus, Payload: `{"broken":`, Stage: model.StageDecode},
+	}}
+	var replayed []string
+	replayErr := errors.New("invalid status")
+	mux := newDeadLetterMux(store, func(ctx context.Context, kind string, data []byte) error {
+		assert.Equal(t, model.DeadLetterStatus, kind)
+		replayed = append(replayed, string(data))
+		return replayErr
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dead-letters/1/replay", nil))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	dl := store.deadLetters[1]
+	assert.Equal(t, model.StageValidate, dl.Stage, "a failed replay records its stage")
+	assert.Equal(t, "invalid status", dl.Error)
+	assert.Nil(t, dl.ReplayedAt)
+
+	replayErr = nil
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dead-letters/1/replay", strings.NewReader(`{"fixed": true}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{`{"broken":`, `{"fixed": true}`}, replayed, "a request body replaces the stored payload")
+	assert.NotNil(t, dl.ReplayedAt)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dead-letters/2/replay", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
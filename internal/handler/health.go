@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable; a nil error means healthy.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type HealthHandler struct {
+	checks []namedCheck
+}
+
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{}
+}
+
+func (h *HealthHandler) AddCheck(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Live reports that the process is up and serving HTTP. It never checks
+// dependencies, so a database outage does not get the pod restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Ready runs every dependency check and answers 503 with per-check detail if
+// any of them fails.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := make(map[string]map[string]string, len(h.checks))
+	for _, c := range h.checks {
+		if err := c.check(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			results[c.name] = map[string]string{"status": "error", "error": err.Error()}
+			continue
+		}
+		results[c.name] = map[string]string{"status": "ok"}
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"status": overall,
+		"checks": results,
+	})
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_ReadyAllHealthy(t *testing.T) {
+	h := NewHealthHandler()
+	h.AddCheck("postgres", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHealthHandler_ReadyReportsFailingDependency(t *testing.T) {
+	h := NewHealthHandler()
+	h.AddCheck("postgres", func(ctx context.Context) error { return nil })
+	h.AddCheck("nats", func(ctx context.Context) error { return errors.New("connection lost") })
+
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var body struct {
+		Status string                       `json:"status"`
+		Checks map[string]map[string]string `json:"checks"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "unavailable", body.Status)
+	assert.Equal(t, "ok", body.Checks["postgres"]["status"])
+	assert.Equal(t, "error", body.Checks["nats"]["status"])
+	assert.Equal(t, "connection lost", body.Checks["nats"]["error"])
+}
+
+func TestHealthHandler_LiveIgnoresDependencies(t *testing.T) {
+	h := NewHealthHandler()
+	h.AddCheck("postgres", func(ctx context.Context) error { return errors.New("down") })
+
+	rec := httptest.NewRecorder()
+	h.Live(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
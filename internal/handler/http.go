@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
+	"log"
 	"net/http"
 	"order-service/internal/cache"
+	"order-service/internal/model"
+	"order-service/internal/repository"
 )
 
 type Handler struct {
@@ -27,9 +31,8 @@ func (h *Handler) GetOrderByUID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, exists := h.cache.Get(orderUID)
-	if !exists {
-		http.Error(w, "Order not found", http.StatusNotFound)
+	order, ok := h.loadOrder(w, r, orderUID)
+	if !ok {
 		return
 	}
 
@@ -44,19 +47,28 @@ func (h *Handler) ShowOrderPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, exists := h.cache.Get(orderUID)
-	if !exists {
-		http.Error(w, "Order not found", http.StatusNotFound)
+	order, ok := h.loadOrder(w, r, orderUID)
+	if !ok {
 		return
 	}
 
 	h.tmpl.Execute(w, order)
 }
 
-func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "ok",
-		"cache_size": h.cache.Size(),
-	})
-}
\ No newline at end of file
+func (h *Handler) loadOrder(w http.ResponseWriter, r *http.Request, orderUID string) (*model.Order, bool) {
+	order, err := h.cache.GetOrLoad(r.Context(), orderUID)
+	if IsNotFound(err) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Failed to load order %s: %v", orderUID, err)
+		http.Error(w, "Failed to load order", http.StatusInternalServerError)
+		return nil, false
+	}
+	return order, true
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, cache.ErrNotFound) || errors.Is(err, repository.ErrOrderNotFound)
+}
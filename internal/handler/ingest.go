@@ -0,0 +1,11 @@
+package handler
+
+import "net/http"
+
+// IngestStatus serves GET /status/ingest. status returns a JSON-serialisable
+// snapshot of the subscriber's progress.
+func IngestStatus(status func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, status())
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"order-service/internal/model"
+)
+
+// OrderIndex is the part of cache.Cache that answers secondary-key lookups.
+type OrderIndex interface {
+	ByTrackNumber(trackNumber string) []*model.Order
+	ByCustomer(customerID string) []*model.Order
+	ByTransaction(transaction string) []*model.Order
+}
+
+// LookupHandler serves support lookups straight from the cache. Orders that
+// are not cached are not found; GET /orders searches the database instead.
+type LookupHandler struct {
+	index OrderIndex
+}
+
+func NewLookupHandler(index OrderIndex) *LookupHandler {
+	return &LookupHandler{index: index}
+}
+
+func (h *LookupHandler) ByTrackNumber(w http.ResponseWriter, r *http.Request) {
+	writeOrders(w, h.index.ByTrackNumber(r.PathValue("track")))
+}
+
+func (h *LookupHandler) ByCustomer(w http.ResponseWriter, r *http.Request) {
+	writeOrders(w, h.index.ByCustomer(r.PathValue("id")))
+}
+
+func (h *LookupHandler) ByTransaction(w http.ResponseWriter, r *http.Request) {
+	writeOrders(w, h.index.ByTransaction(r.PathValue("transaction")))
+}
+
+func writeOrders(w http.ResponseWriter, orders []*model.Order) {
+	if len(orders) == 0 {
+		writeError(w, http.StatusNotFound, "No cached orders found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"orders": orders})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/cache"
+	"order-service/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupHandler(t *testing.T) {
+	orders := cache.New()
+	orders.Set(&model.Order{OrderUID: "a", TrackNumber: "WBILMTESTTRACK", CustomerID: "test",
+		Payment: model.Payment{Transaction: "a"}})
+
+	mux := http.NewServeMux()
+	h := NewLookupHandler(orders)
+	mux.HandleFunc("GET /orders/by-track/{track}", h.ByTrackNumber)
+	mux.HandleFunc("GET /orders/by-transaction/{transaction}", h.ByTransaction)
+	mux.HandleFunc("GET /customers/{id}/orders", h.ByCustomer)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/orders/by-track/WBILMTESTTRACK", http.StatusOK},
+		{"/orders/by-transaction/a", http.StatusOK},
+		{"/customers/test/orders", http.StatusOK},
+		{"/customers/unknown/orders", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.code, rec.Code)
+
+			if tt.code == http.StatusOK {
+				var body struct {
+					Orders []*model.Order `json:"orders"`
+				}
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+				require.Len(t, body.Orders, 1)
+				assert.Equal(t, "a", body.Orders[0].OrderUID)
+			}
+		})
+	}
+}
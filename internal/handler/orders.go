@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"order-service/internal/repository"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultOrdersLimit = 20
+	maxOrdersLimit     = 100
+)
+
+type OrdersHandler struct {
+	orders repository.OrderLister
+}
+
+func NewOrdersHandler(orders repository.OrderLister) *OrdersHandler {
+	return &OrdersHandler{orders: orders}
+}
+
+// List serves GET /orders. Filters: customer_id, track_number,
+// delivery_service, date_from, date_to, provider, currency and brand;
+// paging: cursor and limit.
+func (h *OrdersHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := repository.OrderFilter{
+		CustomerID:      query.Get("customer_id"),
+		TrackNumber:     query.Get("track_number"),
+		DeliveryService: query.Get("delivery_service"),
+		PaymentProvider: query.Get("provider"),
+		Currency:        query.Get("currency"),
+		Brand:           query.Get("brand"),
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseDate(query, "date_from", false); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.CreatedTo, err = parseDate(query, "date_to", true); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := defaultOrdersLimit
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(limit, maxOrdersLimit)
+	}
+
+	page, err := h.orders.ListOrders(r.Context(), filter, query.Get("cursor"), limit)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to list orders: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list orders")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseDate accepts either an RFC 3339 timestamp or a plain YYYY-MM-DD date.
+// With inclusive set, a plain date covers the whole day.
+func parseDate(query url.Values, name string, inclusive bool) (time.Time, error) {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		if inclusive {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New(name + " must be an RFC 3339 timestamp or YYYY-MM-DD date")
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLister struct {
+	filter repository.OrderFilter
+	cursor string
+	limit  int
+	page   *repository.OrderPage
+	err    error
+}
+
+func (f *fakeLister) ListOrders(ctx context.Context, filter repository.OrderFilter, cursor string, limit int) (*repository.OrderPage, error) {
+	f.filter, f.cursor, f.limit = filter, cursor, limit
+	return f.page, f.err
+}
+
+func TestOrdersHandler_ListPassesFilters(t *testing.T) {
+	lister := &fakeLister{page: &repository.OrderPage{
+		Orders:     []*model.Order{{OrderUID: "b563feb7b2b84b6test"}},
+		NextCursor: "next",
+		Total:      3,
+	}}
+	h := NewOrdersHandler(lister)
+
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet,
+		"/orders?customer_id=test&currency=USD&brand=Vivienne+Sabo&date_from=2021-11-01&date_to=2021-11-30T23:59:59Z&cursor=abc&limit=500", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "test", lister.filter.CustomerID)
+	assert.Equal(t, "USD", lister.filter.Currency)
+	assert.Equal(t, "Vivienne Sabo", lister.filter.Brand)
+	assert.Equal(t, time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), lister.filter.CreatedFrom)
+	assert.Equal(t, time.Date(2021, 11, 30, 23, 59, 59, 0, time.UTC), lister.filter.CreatedTo)
+	assert.Equal(t, "abc", lister.cursor)
+	assert.Equal(t, maxOrdersLimit, lister.limit)
+
+	var page repository.OrderPage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&page))
+	assert.Equal(t, "next", page.NextCursor)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Orders, 1)
+}
+
+func TestOrdersHandler_ListBadRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   error
+	}{
+		{"bad limit", "limit=abc", nil},
+		{"zero limit", "limit=0", nil},
+		{"bad date", "date_from=yesterday", nil},
+		{"bad cursor", "cursor=garbage", repository.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewOrdersHandler(&fakeLister{page: &repository.OrderPage{}, err: tt.err})
+
+			rec := httptest.NewRecorder()
+			h.List(rec, httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
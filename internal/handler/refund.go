@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"time"
+)
+
+// RefundFunc records a refund or cancellation and returns the updated order.
+type RefundFunc func(ctx context.Context, event *model.RefundEvent) (*model.Order, error)
+
+type RefundHandler struct {
+	refund RefundFunc
+}
+
+func NewRefundHandler(refund RefundFunc) *RefundHandler {
+	return &RefundHandler{refund: refund}
+}
+
+// Create serves POST /orders/{id}/refunds. The body is a RefundEvent without
+// order_uid; type defaults to refund.
+func (h *RefundHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var event model.RefundEvent
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&event); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	event.OrderUID = r.PathValue("id")
+	if event.Type == "" {
+		event.Type = model.RefundTypeRefund
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	order, err := h.refund(r.Context(), &event)
+	var validationErr *model.ValidationError
+	switch {
+	case err == nil:
+		log.Printf("Refund %s recorded for order %s", event.RefundID, event.OrderUID)
+		writeJSON(w, http.StatusOK, order)
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusBadRequest, validationErr)
+	case errors.Is(err, repository.ErrOrderNotFound):
+		writeError(w, http.StatusNotFound, "Order not found")
+	case errors.Is(err, repository.ErrDuplicateRefund),
+		errors.Is(err, repository.ErrRefundExceedsAmount),
+		errors.Is(err, repository.ErrUnknownItem),
+		errors.Is(err, repository.ErrItemAlreadyRefunded),
+		errors.As(err, new(*model.TransitionError)):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Failed to refund order %s: %v", event.OrderUID, err)
+		writeError(w, http.StatusInternalServerError, "Failed to record refund")
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundHandler_Create(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"refunded", `{"refund_id": "r1", "rids": ["rid1"]}`, nil, http.StatusOK},
+		{"bad body", `{"refund_id":`, nil, http.StatusBadRequest},
+		{"invalid event", `{"type": "chargeback"}`, &model.ValidationError{}, http.StatusBadRequest},
+		{"unknown order", `{"refund_id": "r1"}`, repository.ErrOrderNotFound, http.StatusNotFound},
+		{"too much", `{"refund_id": "r1", "amount": 99999}`, fmt.Errorf("%w: 99999 requested", repository.ErrRefundExceedsAmount), http.StatusConflict},
+		{"already cancelled", `{"refund_id": "r1", "type": "cancel"}`, &model.TransitionError{From: model.StatusCancelled, To: model.StatusCancelled}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *model.RefundEvent
+			h := NewRefundHandler(func(ctx context.Context, event *model.RefundEvent) (*model.Order, error) {
+				got = event
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &model.Order{OrderUID: event.OrderUID}, nil
+			})
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /orders/{id}/refunds", h.Create)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders/test123/refunds", strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.code, rec.Code)
+			if tt.name == "refunded" {
+				assert.Equal(t, "test123", got.OrderUID)
+				assert.Equal(t, model.RefundTypeRefund, got.Type)
+				assert.False(t, got.OccurredAt.IsZero())
+			}
+		})
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	h := RequireToken("secret", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for header, code := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer secret": http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/orders/test123/refunds", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		assert.Equal(t, code, rec.Code, "Authorization: %q", header)
+	}
+}
@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"net/http"
+	"order-service/internal/cache"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order_service"
+
+// Registry holds every metric exposed on /metrics, including Go runtime and
+// process statistics.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	MessagesReceived = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Messages received from NATS.",
+	})
+	MessagesValidated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_validated_total",
+		Help:      "Messages that decoded and passed order validation.",
+	})
+	MessagesRejected = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_rejected_total",
+		Help:      "Messages rejected before persisting, by pipeline stage.",
+	}, []string{"stage"})
+	MessagesPersisted = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_persisted_total",
+		Help:      "Persist attempts by outcome: created, duplicate, conflict or error.",
+	}, []string{"outcome"})
+	MessagesParked = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_parked_total",
+		Help:      "Messages parked after exhausting redeliveries.",
+	})
+	StatusEvents = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "status_events_total",
+		Help:      "Order status events by outcome: applied, unchanged, rejected, unknown_order or error.",
+	}, []string{"outcome"})
+	RefundEvents = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refund_events_total",
+		Help:      "Refund and cancellation events by outcome: applied, duplicate, rejected, unknown_order or error.",
+	}, []string{"outcome"})
+	IngestDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ingest_duration_seconds",
+		Help:      "Time to handle one NATS message from receipt to ack.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	PublishToPersist = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "publish_to_persist_seconds",
+		Help:      "Time from a message being published to NATS to its order being committed.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 900},
+	})
+	IngestLastSequence = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ingest_last_sequence",
+		Help:      "Highest NATS Streaming sequence handled by the subscriber.",
+	})
+	ChannelLastSequence = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "channel_last_sequence",
+		Help:      "Last sequence stored in the orders channel, from the NATS monitoring endpoint.",
+	})
+	ConsumerLag = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_lag_messages",
+		Help:      "Messages in the orders channel beyond the highest handled sequence.",
+	})
+	Workers = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers",
+		Help:      "Size of the message worker pool.",
+	})
+	WorkersBusy = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers_busy",
+		Help:      "Workers currently handling a message.",
+	})
+	WorkerBusySeconds = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_busy_seconds_total",
+		Help:      "Time spent by all workers handling messages; divide its rate by workers for utilization.",
+	})
+	WorkerQueueDepth = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_queue_depth",
+		Help:      "Messages waiting for each worker, including deliveries blocked on a full queue.",
+	}, []string{"worker"})
+	BatchSize = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_size",
+		Help:      "Orders written per batch.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+	BatchFallbacks = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batch_fallbacks_total",
+		Help:      "Batches whose transaction failed, so their orders were written one by one.",
+	})
+	OutboxEvents = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Outbox events relayed to the broker by outcome: published or failed.",
+	}, []string{"outcome"})
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Postgres latency per repository method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveQuery starts timing a repository call; call the returned func when
+// the call finishes.
+func ObserveQuery(method string) func() {
+	start := time.Now()
+	return func() {
+		DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterCache exposes the cache's own counters, read at scrape time.
+func RegisterCache(c *cache.Cache) {
+	stat := func(f func(s cache.Stats) float64) func() float64 {
+		return func() float64 { return f(c.Stats()) }
+	}
+
+	factory.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "Cache lookups served from memory.",
+	}, stat(func(s cache.Stats) float64 { return float64(s.Hits) }))
+	factory.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "Cache lookups that missed.",
+	}, stat(func(s cache.Stats) float64 { return float64(s.Misses) }))
+	factory.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_evictions_total",
+		Help:      "Orders evicted to stay within cache limits.",
+	}, stat(func(s cache.Stats) float64 { return float64(s.Evictions) }))
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_entries",
+		Help:      "Orders currently cached.",
+	}, stat(func(s cache.Stats) float64 { return float64(s.Size) }))
+	factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_bytes",
+		Help:      "Approximate memory held by cached orders.",
+	}, stat(func(s cache.Stats) float64 { return float64(s.Bytes) }))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// InstrumentHTTP records request counts and latency for next, labelled with
+// the ServeMux pattern that matched rather than the raw path.
+func InstrumentHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(rec.status)
+		HTTPRequests.WithLabelValues(route, r.Method, status).Inc()
+		HTTPDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
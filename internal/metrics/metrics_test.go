@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/cache"
+	"order-service/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	c := cache.New()
+	c.Set(&model.Order{OrderUID: "test123"})
+	c.Get("test123")
+	c.Get("missing")
+	RegisterCache(c)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	InstrumentHTTP(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	text := string(body)
+
+	for _, want := range []string{
+		`order_service_http_requests_total{method="GET",route="GET /orders/{id}",status="404"} 1`,
+		`order_service_http_request_duration_seconds_count{method="GET",route="GET /orders/{id}",status="404"} 1`,
+		"order_service_cache_hits_total 1",
+		"order_service_cache_misses_total 1",
+		"order_service_cache_entries 1",
+		"go_goroutines",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected metrics output to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"order-service/migrations"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad_SortsAndPairsFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t(a);")},
+		"0002_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+		"0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+		"0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(got))
+	}
+	if got[0].Version != 1 || got[0].Name != "create_table" || got[1].Version != 2 {
+		t.Errorf("Unexpected order: %+v", got)
+	}
+	if got[1].Down != "DROP INDEX i;" {
+		t.Errorf("Expected down script to be paired, got %q", got[1].Down)
+	}
+}
+
+func TestLoad_RequiresDownFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_table.up.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+	}
+
+	if _, err := Load(fsys); err == nil {
+		t.Error("Expected an error for a migration without a down file")
+	}
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for i, m := range got {
+		if m.Version != i+1 {
+			t.Errorf("Expected contiguous versions, got %d at position %d", m.Version, i)
+		}
+	}
+}
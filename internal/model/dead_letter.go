@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Pipeline stages at which an incoming message can be rejected.
+const (
+	StageDecode   = "decode"
+	StageValidate = "validate"
+	StagePersist  = "persist"
+)
+
+// Kinds of messages stored as dead letters; the kind decides how a dead
+// letter is replayed.
+const (
+	DeadLetterOrder  = "order"
+	DeadLetterStatus = "status"
+	DeadLetterRefund = "refund"
+)
+
+type DeadLetter struct {
+	ID         int64      `json:"id" db:"id"`
+	Kind       string     `json:"kind" db:"kind"`
+	Channel    string     `json:"channel" db:"channel"`
+	Payload    string     `json:"payload" db:"payload"`
+	Sequence   uint64     `json:"sequence" db:"sequence"`
+	Timestamp  time.Time  `json:"timestamp" db:"published_at"`
+	Stage      string     `json:"stage" db:"stage"`
+	Error      string     `json:"error" db:"error"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReplayedAt *time.Time `json:"replayed_at,omitempty" db:"replayed_at"`
+}
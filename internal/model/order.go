@@ -2,7 +2,6 @@ package model
 
 import (
 	"encoding/json"
-        "fmt"
 	"time"
 )
 
@@ -21,6 +20,11 @@ type Order struct {
 	SmID              int       `json:"sm_id" db:"sm_id"`
 	DateCreated       time.Time `json:"date_created" db:"date_created"`
 	OofShard          string    `json:"oof_shard" db:"oof_shard"`
+
+	// Status and StatusHistory are maintained by the service; values in
+	// incoming orders are ignored.
+	Status        OrderStatus    `json:"status,omitempty" db:"status"`
+	StatusHistory []StatusChange `json:"status_history,omitempty" db:"-"`
 }
 
 type Delivery struct {
@@ -46,6 +50,10 @@ type Payment struct {
 	DeliveryCost int    `json:"delivery_cost" db:"delivery_cost"`
 	GoodsTotal   int    `json:"goods_total" db:"goods_total"`
 	CustomFee    int    `json:"custom_fee" db:"custom_fee"`
+
+	// Refunds are recorded by the service; values in incoming orders are ignored.
+	Refunds  []Refund `json:"refunds,omitempty" db:"-"`
+	Refunded int      `json:"refunded,omitempty" db:"-"`
 }
 
 type Item struct {
@@ -61,22 +69,7 @@ type Item struct {
 	NmID        int    `json:"nm_id" db:"nm_id"`
 	Brand       string `json:"brand" db:"brand"`
 	Status      int    `json:"status" db:"status"`
-}
-
-func (o *Order) Validate() error {
-	if o.OrderUID == "" {
-		return fmt.Errorf("order_uid is required")
-	}
-	if o.TrackNumber == "" {
-		return fmt.Errorf("track_number is required")
-	}
-	if o.Delivery.Name == "" {
-		return fmt.Errorf("delivery name is required")
-	}
-	if len(o.Items) == 0 {
-		return fmt.Errorf("items cannot be empty")
-	}
-	return nil
+	Refunded    bool   `json:"refunded,omitempty" db:"-"`
 }
 
 func (o *Order) FromJSON(data []byte) error {
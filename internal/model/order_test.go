@@ -1,20 +1,35 @@
 package model
 
 import (
+	"errors"
 	"testing"
 )
 
-func TestOrderValidation_ValidOrder(t *testing.T) {
-	order := &Order{
+func validOrder() *Order {
+	return &Order{
 		OrderUID:    "test123",
 		TrackNumber: "TRACK123",
+		Locale:      "en",
 		Delivery: Delivery{
-			Name: "Test User",
+			Name:  "Test User",
+			Phone: "+9720000000",
+			Email: "test@example.com",
+		},
+		Payment: Payment{
+			Transaction:  "test123",
+			Currency:     "USD",
+			Amount:       1817,
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
 		},
 		Items: []Item{
-			{Name: "Test Item"},
+			{Name: "Test Item", TrackNumber: "TRACK123", Price: 453, Sale: 30, TotalPrice: 317},
 		},
 	}
+}
+
+func TestOrderValidation_ValidOrder(t *testing.T) {
+	order := validOrder()
 
 	if err := order.Validate(); err != nil {
 		t.Errorf("Valid order should not fail validation: %v", err)
@@ -23,56 +38,62 @@ func TestOrderValidation_ValidOrder(t *testing.T) {
 
 func TestOrderValidation_InvalidOrder(t *testing.T) {
 	tests := []struct {
-		name  string
-		order *Order
+		name   string
+		mutate func(o *Order)
+		path   string
 	}{
-		{
-			name:  "empty order",
-			order: &Order{},
-		},
-		{
-			name: "missing order_uid",
-			order: &Order{
-				TrackNumber: "TRACK123",
-				Delivery: Delivery{Name: "Test"},
-				Items:      []Item{{Name: "Test"}},
-			},
-		},
-		{
-			name: "missing delivery name", 
-			order: &Order{
-				OrderUID:    "test123",
-				TrackNumber: "TRACK123",
-				Delivery:    Delivery{},
-				Items:       []Item{{Name: "Test"}},
-			},
-		},
-		{
-			name: "empty items",
-			order: &Order{
-				OrderUID:    "test123", 
-				TrackNumber: "TRACK123",
-				Delivery:    Delivery{Name: "Test"},
-				Items:       []Item{},
-			},
-		},
+		{"missing order_uid", func(o *Order) { o.OrderUID = "" }, "order_uid"},
+		{"missing track_number", func(o *Order) { o.TrackNumber = "" }, "track_number"},
+		{"missing delivery name", func(o *Order) { o.Delivery.Name = "" }, "delivery.name"},
+		{"empty items", func(o *Order) { o.Items = []Item{} }, "items"},
+		{"invalid email", func(o *Order) { o.Delivery.Email = "not-an-email" }, "delivery.email"},
+		{"invalid phone", func(o *Order) { o.Delivery.Phone = "call me" }, "delivery.phone"},
+		{"unknown currency", func(o *Order) { o.Payment.Currency = "XXX1" }, "payment.currency"},
+		{"invalid locale", func(o *Order) { o.Locale = "english" }, "locale"},
+		{"negative delivery cost", func(o *Order) { o.Payment.DeliveryCost = -1 }, "payment.delivery_cost"},
+		{"transaction mismatch", func(o *Order) { o.Payment.Transaction = "other" }, "payment.transaction"},
+		{"item track mismatch", func(o *Order) { o.Items[0].TrackNumber = "OTHER" }, "items[0].track_number"},
+		{"goods total mismatch", func(o *Order) { o.Payment.GoodsTotal = 300 }, "payment.goods_total"},
+		{"amount mismatch", func(o *Order) { o.Payment.Amount = 1000 }, "payment.amount"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.order.Validate(); err == nil {
-				t.Error("Expected validation error but got none")
+			order := validOrder()
+			tt.mutate(order)
+
+			err := order.Validate()
+			if err == nil {
+				t.Fatal("Expected validation error but got none")
+			}
+			if !hasPath(err, tt.path) {
+				t.Errorf("Expected error for %s, got %v", tt.path, err)
 			}
 		})
 	}
 }
 
+func TestOrderValidation_EmptyOrderListsAllErrors(t *testing.T) {
+	err := (&Order{}).Validate()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *ValidationError, got %T", err)
+	}
+	for _, path := range []string{"order_uid", "track_number", "delivery.name", "items", "payment.currency"} {
+		if !hasPath(err, path) {
+			t.Errorf("Expected error for %s in %v", path, err)
+		}
+	}
+}
+
 func TestOrderFromJSON(t *testing.T) {
 	jsonData := `{
 		"order_uid": "test123",
 		"track_number": "TRACK123",
 		"delivery": {"name": "Test User"},
-		"items": [{"name": "Test Item"}]
+		"payment": {"transaction": "test123", "currency": "USD", "amount": 100, "goods_total": 100},
+		"items": [{"name": "Test Item", "track_number": "TRACK123", "total_price": 100}]
 	}`
 
 	var order Order
@@ -84,4 +105,17 @@ func TestOrderFromJSON(t *testing.T) {
 	if order.OrderUID != "test123" {
 		t.Errorf("Expected OrderUID 'test123', got '%s'", order.OrderUID)
 	}
-}
\ No newline at end of file
+}
+
+func hasPath(err error, path string) bool {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return false
+	}
+	for _, fe := range verr.Errors {
+		if fe.Path == path {
+			return true
+		}
+	}
+	return false
+}
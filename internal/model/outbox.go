@@ -0,0 +1,51 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const EventOrderAccepted = "order.accepted"
+
+// OutboxEvent is an event written in the same transaction as the change it
+// announces and published to the broker afterwards. EventID is stable across
+// publish attempts, so consumers and the broker can drop repeats.
+type OutboxEvent struct {
+	ID        int64     `json:"id" db:"id"`
+	EventID   string    `json:"event_id" db:"event_id"`
+	Type      string    `json:"type" db:"event_type"`
+	OrderUID  string    `json:"order_uid" db:"order_uid"`
+	Payload   []byte    `json:"payload" db:"payload"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// OrderAccepted is the message published for a newly stored order.
+type OrderAccepted struct {
+	EventID    string    `json:"event_id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Order      *Order    `json:"order"`
+}
+
+// NewOrderAcceptedEvent builds the outbox event for an order that has just
+// been stored with its initial status.
+func NewOrderAcceptedEvent(order *Order) (*OutboxEvent, error) {
+	accepted := OrderAccepted{
+		EventID: EventOrderAccepted + ":" + order.OrderUID,
+		Type:    EventOrderAccepted,
+		Order:   order,
+	}
+	if len(order.StatusHistory) > 0 {
+		accepted.OccurredAt = order.StatusHistory[0].ChangedAt
+	}
+	payload, err := json.Marshal(accepted)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		EventID:  accepted.EventID,
+		Type:     accepted.Type,
+		OrderUID: order.OrderUID,
+		Payload:  payload,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewOrderAcceptedEvent(t *testing.T) {
+	accepted := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := &Order{
+		OrderUID:      "test123",
+		Status:        StatusCreated,
+		StatusHistory: []StatusChange{{Status: StatusCreated, ChangedAt: accepted}},
+	}
+
+	event, err := NewOrderAcceptedEvent(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.EventID != "order.accepted:test123" || event.Type != EventOrderAccepted || event.OrderUID != "test123" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	var payload OrderAccepted
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.EventID != event.EventID || !payload.OccurredAt.Equal(accepted) || payload.Order.Status != StatusCreated {
+		t.Errorf("Unexpected payload %s", event.Payload)
+	}
+}
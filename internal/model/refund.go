@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	RefundTypeRefund = "refund"
+	RefundTypeCancel = "cancel"
+)
+
+// Refund is money returned against an order's payment, optionally for
+// specific items.
+type Refund struct {
+	RefundID  string    `json:"refund_id" db:"refund_id"`
+	Amount    int       `json:"amount" db:"amount"`
+	Rids      []string  `json:"rids,omitempty" db:"-"`
+	Reason    string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// RefundEvent asks for a refund or a cancellation of an order. RefundID makes
+// the event idempotent.
+//
+// A refund with Rids returns those items, by default for their total_price.
+// A refund without Rids returns Amount, or whatever has not been refunded yet
+// when Amount is zero. A cancellation moves the order to cancelled and
+// refunds the remainder.
+type RefundEvent struct {
+	RefundID   string    `json:"refund_id"`
+	OrderUID   string    `json:"order_uid"`
+	Type       string    `json:"type"`
+	Rids       []string  `json:"rids,omitempty"`
+	Amount     int       `json:"amount,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func (e *RefundEvent) FromJSON(data []byte) error {
+	if err := json.Unmarshal(data, e); err != nil {
+		return err
+	}
+	return e.Validate()
+}
+
+func (e *RefundEvent) Validate() error {
+	v := &ValidationError{}
+	if e.RefundID == "" {
+		v.add("refund_id", "is required")
+	}
+	if e.OrderUID == "" {
+		v.add("order_uid", "is required")
+	}
+	switch e.Type {
+	case RefundTypeRefund:
+	case RefundTypeCancel:
+		if len(e.Rids) > 0 || e.Amount != 0 {
+			v.add("type", "cancel refunds the whole remainder and takes no rids or amount")
+		}
+	default:
+		v.add("type", "must be refund or cancel")
+	}
+	if e.Amount < 0 {
+		v.add("amount", "must not be negative")
+	}
+	seen := make(map[string]bool, len(e.Rids))
+	for i, rid := range e.Rids {
+		path := fmt.Sprintf("rids[%d]", i)
+		if rid == "" {
+			v.add(path, "is required")
+		} else if seen[rid] {
+			v.add(path, "duplicates %s", rid)
+		}
+		seen[rid] = true
+	}
+	if len(v.Errors) > 0 {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefundEvent_Validate(t *testing.T) {
+	valid := []*RefundEvent{
+		{RefundID: "r1", OrderUID: "test123", Type: RefundTypeRefund, Rids: []string{"rid1"}},
+		{RefundID: "r2", OrderUID: "test123", Type: RefundTypeRefund, Amount: 100},
+		{RefundID: "r3", OrderUID: "test123", Type: RefundTypeCancel, OccurredAt: time.Now()},
+	}
+	for _, event := range valid {
+		if err := event.Validate(); err != nil {
+			t.Errorf("Event %s should be valid: %v", event.RefundID, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		event *RefundEvent
+		path  string
+	}{
+		{"missing refund id", &RefundEvent{OrderUID: "test123", Type: RefundTypeRefund}, "refund_id"},
+		{"missing order", &RefundEvent{RefundID: "r1", Type: RefundTypeRefund}, "order_uid"},
+		{"unknown type", &RefundEvent{RefundID: "r1", OrderUID: "test123", Type: "chargeback"}, "type"},
+		{"cancel with items", &RefundEvent{RefundID: "r1", OrderUID: "test123", Type: RefundTypeCancel, Rids: []string{"rid1"}}, "type"},
+		{"negative amount", &RefundEvent{RefundID: "r1", OrderUID: "test123", Type: RefundTypeRefund, Amount: -1}, "amount"},
+		{"duplicate rid", &RefundEvent{RefundID: "r1", OrderUID: "test123", Type: RefundTypeRefund, Rids: []string{"rid1", "rid1"}}, "rids[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.event.Validate(); !hasPath(err, tt.path) {
+				t.Errorf("Expected an error for %s, got %v", tt.path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type OrderStatus string
+
+const (
+	StatusCreated    OrderStatus = "created"
+	StatusPaid       OrderStatus = "paid"
+	StatusAssembling OrderStatus = "assembling"
+	StatusShipped    OrderStatus = "shipped"
+	StatusDelivered  OrderStatus = "delivered"
+	StatusCancelled  OrderStatus = "cancelled"
+	StatusReturned   OrderStatus = "returned"
+)
+
+// transitions lists the statuses reachable from each status. Cancelled and
+// returned are final.
+var transitions = map[OrderStatus][]OrderStatus{
+	StatusCreated:    {StatusPaid, StatusCancelled},
+	StatusPaid:       {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered, StatusReturned},
+	StatusDelivered:  {StatusReturned},
+	StatusCancelled:  nil,
+	StatusReturned:   nil,
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanTransition reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransition(next OrderStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type StatusChange struct {
+	Status    OrderStatus `json:"status" db:"status"`
+	Reason    string      `json:"reason,omitempty" db:"reason"`
+	ChangedAt time.Time   `json:"changed_at" db:"changed_at"`
+}
+
+// StatusEvent is a status-change message for an existing order.
+type StatusEvent struct {
+	OrderUID   string      `json:"order_uid"`
+	Status     OrderStatus `json:"status"`
+	Reason     string      `json:"reason,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+func (e *StatusEvent) FromJSON(data []byte) error {
+	if err := json.Unmarshal(data, e); err != nil {
+		return err
+	}
+	return e.Validate()
+}
+
+func (e *StatusEvent) Validate() error {
+	v := &ValidationError{}
+	if e.OrderUID == "" {
+		v.add("order_uid", "is required")
+	}
+	if !e.Status.Valid() {
+		v.add("status", "must be one of created, paid, assembling, shipped, delivered, cancelled, returned")
+	}
+	if e.OccurredAt.IsZero() {
+		v.add("occurred_at", "is required")
+	}
+	if len(v.Errors) > 0 {
+		return v
+	}
+	return nil
+}
+
+// TransitionError reports a status change the state machine does not allow.
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change order status from %s to %s", e.From, e.To)
+}
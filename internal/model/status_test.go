@@ -0,0 +1,45 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderStatus_CanTransition(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{StatusCreated, StatusPaid, true},
+		{StatusPaid, StatusAssembling, true},
+		{StatusAssembling, StatusShipped, true},
+		{StatusShipped, StatusDelivered, true},
+		{StatusDelivered, StatusReturned, true},
+		{StatusCreated, StatusCancelled, true},
+		{StatusCreated, StatusShipped, false},
+		{StatusShipped, StatusCancelled, false},
+		{StatusCancelled, StatusPaid, false},
+		{StatusReturned, StatusDelivered, false},
+		{StatusPaid, StatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+func TestStatusEvent_Validate(t *testing.T) {
+	event := &StatusEvent{OrderUID: "test123", Status: StatusPaid, OccurredAt: time.Now()}
+	if err := event.Validate(); err != nil {
+		t.Errorf("Valid event should not fail validation: %v", err)
+	}
+
+	err := (&StatusEvent{Status: "lost"}).Validate()
+	for _, path := range []string{"order_uid", "status", "occurred_at"} {
+		if !hasPath(err, path) {
+			t.Errorf("Expected an error for %s, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var (
+	phonePattern  = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+	localePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+)
+
+// currencies holds the active ISO 4217 currency codes.
+var currencies = map[string]bool{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB
+		BRL BSD BTN BWP BYN BZD CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP
+		DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF
+		IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK
+		LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN
+		NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF
+		SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND
+		TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XOF XPF YER
+		ZAR ZMW ZWL
+	`) {
+		currencies[code] = true
+	}
+}
+
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field of an order that failed validation.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fe.Path + ": " + fe.Message
+	}
+	return "invalid order: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (o *Order) Validate() error {
+	v := &ValidationError{}
+
+	if o.OrderUID == "" {
+		v.add("order_uid", "is required")
+	}
+	if o.TrackNumber == "" {
+		v.add("track_number", "is required")
+	}
+	if o.Locale != "" && !localePattern.MatchString(o.Locale) {
+		v.add("locale", "%q is not a valid locale code", o.Locale)
+	}
+
+	o.Delivery.validate(v)
+	o.Payment.validate(v, o.OrderUID)
+
+	if len(o.Items) == 0 {
+		v.add("items", "cannot be empty")
+	}
+	goodsTotal := 0
+	for i := range o.Items {
+		o.Items[i].validate(v, fmt.Sprintf("items[%d]", i), o.TrackNumber)
+		goodsTotal += o.Items[i].TotalPrice
+	}
+
+	p := o.Payment
+	if len(o.Items) > 0 && p.GoodsTotal != goodsTotal {
+		v.add("payment.goods_total", "is %d, expected sum of items total_price %d", p.GoodsTotal, goodsTotal)
+	}
+	if expected := p.GoodsTotal + p.DeliveryCost + p.CustomFee; p.Amount != expected {
+		v.add("payment.amount", "is %d, expected goods_total + delivery_cost + custom_fee = %d", p.Amount, expected)
+	}
+
+	if len(v.Errors) > 0 {
+		return v
+	}
+	return nil
+}
+
+func (d *Delivery) validate(v *ValidationError) {
+	if d.Name == "" {
+		v.add("delivery.name", "is required")
+	}
+	if d.Phone != "" && !phonePattern.MatchString(d.Phone) {
+		v.add("delivery.phone", "%q is not a valid phone number", d.Phone)
+	}
+	if d.Email != "" {
+		if addr, err := mail.ParseAddress(d.Email); err != nil || addr.Address != d.Email {
+			v.add("delivery.email", "%q is not a valid email address", d.Email)
+		}
+	}
+}
+
+func (p *Payment) validate(v *ValidationError, orderUID string) {
+	if p.Transaction != orderUID {
+		v.add("payment.transaction", "must match order_uid %q", orderUID)
+	}
+	if !currencies[p.Currency] {
+		v.add("payment.currency", "%q is not an ISO 4217 currency code", p.Currency)
+	}
+	nonNegative(v, "payment.amount", p.Amount)
+	nonNegative(v, "payment.delivery_cost", p.DeliveryCost)
+	nonNegative(v, "payment.goods_total", p.GoodsTotal)
+	nonNegative(v, "payment.custom_fee", p.CustomFee)
+}
+
+func (i *Item) validate(v *ValidationError, path, trackNumber string) {
+	if i.TrackNumber != trackNumber {
+		v.add(path+".track_number", "must match order track_number %q", trackNumber)
+	}
+	nonNegative(v, path+".price", i.Price)
+	nonNegative(v, path+".total_price", i.TotalPrice)
+	if i.Sale < 0 || i.Sale > 100 {
+		v.add(path+".sale", "must be between 0 and 100, got %d", i.Sale)
+	}
+}
+
+func nonNegative(v *ValidationError, path string, value int) {
+	if value < 0 {
+		v.add(path, "must not be negative, got %d", value)
+	}
+}
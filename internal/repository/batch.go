@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// BatchWriter stores many orders at once. The result holds one error per
+// order, in the same order, with the same meaning as CreateOrder's.
+type BatchWriter interface {
+	CreateOrders(ctx context.Context, orders []*model.Order) []error
+}
+
+// CreateOrders writes orders not yet stored in one transaction, copying them
+// into every table with COPY. Orders whose UID is already stored, or repeats
+// a UID earlier in the batch, go through CreateOrder afterwards so that
+// duplicates and conflicts are handled as usual. If the batch transaction
+// fails, each of its orders is retried with CreateOrder, so a poisoned order
+// fails on its own.
+func (r *PostgresRepository) CreateOrders(ctx context.Context, orders []*model.Order) []error {
+	defer metrics.ObserveQuery("CreateOrders")()
+
+	var batch, single []int
+	if stored, err := r.storedUIDs(ctx, orders); err != nil {
+		single = allIndexes(orders)
+	} else {
+		batch, single = splitBatch(orders, stored)
+	}
+
+	if len(batch) > 0 {
+		fresh := make([]*model.Order, len(batch))
+		for i, idx := range batch {
+			fresh[i] = orders[idx]
+		}
+		if err := r.copyOrders(ctx, fresh); err != nil {
+			metrics.BatchFallbacks.Inc()
+			single = append(single, batch...)
+			slices.Sort(single)
+		}
+	}
+
+	errs := make([]error, len(orders))
+	for _, idx := range single {
+		errs[idx] = r.CreateOrder(ctx, orders[idx])
+	}
+	return errs
+}
+
+func (r *PostgresRepository) storedUIDs(ctx context.Context, orders []*model.Order) (map[string]bool, error) {
+	uids := make([]string, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_uid FROM orders WHERE order_uid = ANY($1)
+	`, pq.Array(uids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]bool)
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		stored[uid] = true
+	}
+	return stored, rows.Err()
+}
+
+// splitBatch returns the indexes of orders that can be copied in one go and
+// of those that must go through CreateOrder: already stored UIDs and every
+// repeat of a UID after its first occurrence.
+func splitBatch(orders []*model.Order, stored map[string]bool) (batch, single []int) {
+	seen := make(map[string]bool)
+	for i, order := range orders {
+		if stored[order.OrderUID] || seen[order.OrderUID] {
+			single = append(single, i)
+			continue
+		}
+		seen[order.OrderUID] = true
+		batch = append(batch, i)
+	}
+	return batch, single
+}
+
+func allIndexes(orders []*model.Order) []int {
+	idx := make([]int, len(orders))
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// copyOrders inserts new orders with their delivery, payment, items, initial
+// status and, with the outbox enabled, order.accepted events in one
+// transaction. A concurrent insert of one of the UIDs
+// makes the COPY into orders fail, and with it the whole batch.
+func (r *PostgresRepository) copyOrders(ctx context.Context, orders []*model.Order) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = copyRows(ctx, tx, "orders", []string{
+		"order_uid", "track_number", "entry", "locale", "internal_signature",
+		"customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	}, func(add func(values ...any) error) error {
+		for _, o := range orders {
+			err := add(o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature,
+				o.CustomerID, o.DeliveryService, o.Shardkey, o.SmID, o.DateCreated, o.OofShard)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = copyRows(ctx, tx, "delivery", []string{
+		"order_uid", "name", "phone", "zip", "city", "address", "region", "email",
+	}, func(add func(values ...any) error) error {
+		for _, o := range orders {
+			d := o.Delivery
+			if err := add(o.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = copyRows(ctx, tx, "payment", []string{
+		"order_uid", "transaction", "request_id", "currency", "provider",
+		"amount", "payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee",
+	}, func(add func(values ...any) error) error {
+		for _, o := range orders {
+			p := o.Payment
+			err := add(o.OrderUID, p.Transaction, p.RequestID, p.Currency, p.Provider,
+				p.Amount, p.PaymentDt, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = copyRows(ctx, tx, "items", []string{
+		"order_uid", "chrt_id", "track_number", "price", "rid", "name",
+		"sale", "size", "total_price", "nm_id", "brand", "status",
+	}, func(add func(values ...any) error) error {
+		for _, o := range orders {
+			for _, item := range o.Items {
+				err := add(o.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
+					item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// COPY cannot return the default changed_at, so the batch is stamped here.
+	created := model.StatusChange{Status: model.StatusCreated, ChangedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	err = copyRows(ctx, tx, "order_status_history", []string{
+		"order_uid", "status", "reason", "changed_at",
+	}, func(add func(values ...any) error) error {
+		for _, o := range orders {
+			if err := add(o.OrderUID, created.Status, created.Reason, created.ChangedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// A failed batch is retried order by order, which sets these again.
+	for _, o := range orders {
+		initServerFields(o, created)
+	}
+
+	if r.outbox {
+		err = copyRows(ctx, tx, "outbox", []string{
+			"event_id", "event_type", "order_uid", "payload",
+		}, func(add func(values ...any) error) error {
+			for _, o := range orders {
+				event, err := model.NewOrderAcceptedEvent(o)
+				if err != nil {
+					return err
+				}
+				if err := add(event.EventID, event.Type, event.OrderUID, string(event.Payload)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// copyRows streams the rows produced by rows into table with COPY FROM STDIN.
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows func(add func(values ...any) error) error) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = rows(func(values ...any) error {
+		_, err := stmt.ExecContext(ctx, values...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+	"order-service/internal/repository/repotest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestRepository connects to a fresh schema in TEST_DATABASE_URL and
+// skips the test without one.
+func newTestRepository(t *testing.T) *PostgresRepository {
+	t.Helper()
+	r, err := NewPostgresRepository(repotest.DSN(t))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func testOrder(uid string) *model.Order {
+	return &model.Order{
+		OrderUID:        uid,
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:        "1",
+		Delivery:        model.Delivery{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin", Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+		Payment:         model.Payment{Transaction: uid, Currency: "USD", Provider: "wbpay", Amount: 1817, PaymentDt: 1637907727, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []model.Item{
+			{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest", Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202},
+			{ChrtID: 9934931, TrackNumber: "WBILMTESTTRACK", Price: 100, Rid: "ab4219087a764ae0btes2", Name: "Brush", Size: "0", TotalPrice: 100, NmID: 2389213, Brand: "Vivienne Sabo", Status: 202},
+		},
+	}
+}
+
+func countRows(t *testing.T, r *PostgresRepository, table string) int {
+	t.Helper()
+	var n int
+	if err := r.db.QueryRow("SELECT count(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestCreateOrders_CopiesEveryTable(t *testing.T) {
+	r := newTestRepository(t)
+	r.SetOutbox(true)
+
+	errs := r.CreateOrders(context.Background(), []*model.Order{testOrder("a"), testOrder("b")})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+
+	want := map[string]int{"orders": 2, "delivery": 2, "payment": 2, "items": 4, "order_status_history": 2, "outbox": 2}
+	for table, n := range want {
+		if got := countRows(t, r, table); got != n {
+			t.Errorf("Expected %d rows in %s, got %d", n, table, got)
+		}
+	}
+
+	stored, err := r.GetOrderByUID(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	if !sameOrder(stored, testOrder("a")) {
+		t.Error("Expected the copied order to read back unchanged")
+	}
+	if stored.Status != model.StatusCreated || len(stored.StatusHistory) != 1 {
+		t.Errorf("Expected a single created status, got %q with %d changes", stored.Status, len(stored.StatusHistory))
+	}
+}
+
+func TestCreateOrders_DedupsStoredUIDs(t *testing.T) {
+	r := newTestRepository(t)
+	if err := r.CreateOrder(context.Background(), testOrder("a")); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	fallbacks := testutil.ToFloat64(metrics.BatchFallbacks)
+
+	errs := r.CreateOrders(context.Background(), []*model.Order{testOrder("a"), testOrder("b"), testOrder("b")})
+
+	if !errors.Is(errs[0], ErrDuplicateOrder) || errs[1] != nil || !errors.Is(errs[2], ErrDuplicateOrder) {
+		t.Errorf("Expected [ErrDuplicateOrder <nil> ErrDuplicateOrder], got %v", errs)
+	}
+	if got := testutil.ToFloat64(metrics.BatchFallbacks); got != fallbacks {
+		t.Error("Expected stored and repeated UIDs to stay out of the COPY")
+	}
+	if got := countRows(t, r, "orders"); got != 2 {
+		t.Errorf("Expected 2 orders, got %d", got)
+	}
+	if got := countRows(t, r, "items"); got != 4 {
+		t.Errorf("Expected the items of each order once, got %d", got)
+	}
+}
+
+func TestCreateOrders_IsolatesPoisonedOrder(t *testing.T) {
+	r := newTestRepository(t)
+	r.SetOutbox(true)
+	poisoned := testOrder("poison")
+	poisoned.Items[1].Size = strings.Repeat("X", 11)
+	fallbacks := testutil.ToFloat64(metrics.BatchFallbacks)
+
+	errs := r.CreateOrders(context.Background(), []*model.Order{testOrder("a"), poisoned, testOrder("c")})
+
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Fatalf("Expected only the poisoned order to fail, got %v", errs)
+	}
+	if got := testutil.ToFloat64(metrics.BatchFallbacks); got != fallbacks+1 {
+		t.Errorf("Expected one batch fallback, got %v", got-fallbacks)
+	}
+	if _, err := r.GetOrderByUID(context.Background(), "poison"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected no part of the poisoned order to be stored, got %v", err)
+	}
+	want := map[string]int{"orders": 2, "items": 4, "order_status_history": 2, "outbox": 2}
+	for table, n := range want {
+		if got := countRows(t, r, table); got != n {
+			t.Errorf("Expected %d rows in %s, got %d", n, table, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+type DeadLetterFilter struct {
+	Kind     string
+	Stage    string
+	BeforeID int64
+	Limit    int
+}
+
+type DeadLetterStore interface {
+	SaveDeadLetter(ctx context.Context, dl *model.DeadLetter) error
+	ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]*model.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id int64) (*model.DeadLetter, error)
+	MarkDeadLetterReplayed(ctx context.Context, id int64) error
+	UpdateDeadLetterError(ctx context.Context, id int64, stage, errText string) error
+}
+
+func (r *PostgresRepository) SaveDeadLetter(ctx context.Context, dl *model.DeadLetter) error {
+	defer metrics.ObserveQuery("SaveDeadLetter")()
+
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO dead_letters (kind, channel, payload, sequence, published_at, stage, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, deadLetterKind(dl.Kind), dl.Channel, []byte(dl.Payload), int64(dl.Sequence), dl.Timestamp, dl.Stage, dl.Error,
+	).Scan(&dl.ID, &dl.CreatedAt)
+}
+
+func deadLetterKind(kind string) string {
+	if kind == "" {
+		return model.DeadLetterOrder
+	}
+	return kind
+}
+
+// ListDeadLetters returns dead letters newest first. BeforeID pages through
+// older entries and an empty Kind or Stage matches every kind or stage.
+func (r *PostgresRepository) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]*model.DeadLetter, error) {
+	defer metrics.ObserveQuery("ListDeadLetters")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, kind, channel, payload, sequence, published_at, stage, error, created_at, replayed_at
+		FROM dead_letters
+		WHERE ($1 = '' OR stage = $1) AND ($2::bigint = 0 OR id < $2::bigint) AND ($4 = '' OR kind = $4)
+		ORDER BY id DESC
+		LIMIT $3
+	`, filter.Stage, filter.BeforeID, filter.Limit, filter.Kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadLetters := []*model.DeadLetter{}
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, dl)
+	}
+	return deadLetters, rows.Err()
+}
+
+func (r *PostgresRepository) GetDeadLetter(ctx context.Context, id int64) (*model.DeadLetter, error) {
+	defer metrics.ObserveQuery("GetDeadLetter")()
+
+	dl, err := scanDeadLetter(r.db.QueryRowContext(ctx, `
+		SELECT id, kind, channel, payload, sequence, published_at, stage, error, created_at, replayed_at
+		FROM dead_letters WHERE id = $1
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return dl, err
+}
+
+func (r *PostgresRepository) MarkDeadLetterReplayed(ctx context.Context, id int64) error {
+	defer metrics.ObserveQuery("MarkDeadLetterReplayed")()
+
+	return r.execDeadLetter(ctx, "UPDATE dead_letters SET replayed_at = now() WHERE id = $1", id)
+}
+
+func (r *PostgresRepository) UpdateDeadLetterError(ctx context.Context, id int64, stage, errText string) error {
+	defer metrics.ObserveQuery("UpdateDeadLetterError")()
+
+	return r.execDeadLetter(ctx, "UPDATE dead_letters SET stage = $2, error = $3 WHERE id = $1", id, stage, errText)
+}
+
+func (r *PostgresRepository) execDeadLetter(ctx context.Context, query string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDeadLetter(row rowScanner) (*model.DeadLetter, error) {
+	var (
+		dl         model.DeadLetter
+		payload    []byte
+		sequence   int64
+		replayedAt sql.NullTime
+	)
+	err := row.Scan(&dl.ID, &dl.Kind, &dl.Channel, &payload, &sequence, &dl.Timestamp, &dl.Stage, &dl.Error, &dl.CreatedAt, &replayedAt)
+	if err != nil {
+		return nil, err
+	}
+	dl.Payload = string(payload)
+	dl.Sequence = uint64(sequence)
+	if replayedAt.Valid {
+		dl.ReplayedAt = &replayedAt.Time
+	}
+	return &dl, nil
+}
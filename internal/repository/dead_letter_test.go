@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/model"
+	"slices"
+	"testing"
+	"time"
+)
+
+func saveDeadLetters(t *testing.T, r *PostgresRepository, dls ...*model.DeadLetter) {
+	t.Helper()
+	for _, dl := range dls {
+		dl.Timestamp = time.Now()
+		if err := r.SaveDeadLetter(context.Background(), dl); err != nil {
+			t.Fatalf("SaveDeadLetter: %v", err)
+		}
+	}
+}
+
+func deadLetterIDs(dls []*model.DeadLetter) []int64 {
+	ids := make([]int64, len(dls))
+	for i, dl := range dls {
+		ids[i] = dl.ID
+	}
+	return ids
+}
+
+func TestListDeadLetters(t *testing.T) {
+	r := newTestRepository(t)
+	order := &model.DeadLetter{Payload: `{}`, Stage: model.StageDecode}
+	status := &model.DeadLetter{Kind: model.DeadLetterStatus, Channel: "order-status", Payload: `{}`, Stage: model.StagePersist}
+	refund := &model.DeadLetter{Kind: model.DeadLetterRefund, Channel: "order-refunds", Payload: `{}`, Stage: model.StagePersist}
+	saveDeadLetters(t, r, order, status, refund)
+
+	tests := []struct {
+		name   string
+		filter DeadLetterFilter
+		want   []int64
+	}{
+		{"all", DeadLetterFilter{Limit: 10}, []int64{refund.ID, status.ID, order.ID}},
+		{"limit", DeadLetterFilter{Limit: 2}, []int64{refund.ID, status.ID}},
+		{"before", DeadLetterFilter{Limit: 10, BeforeID: refund.ID}, []int64{status.ID, order.ID}},
+		{"kind", DeadLetterFilter{Limit: 10, Kind: model.DeadLetterOrder}, []int64{order.ID}},
+		{"stage", DeadLetterFilter{Limit: 10, Stage: model.StagePersist}, []int64{refund.ID, status.ID}},
+		{"kind and stage", DeadLetterFilter{Limit: 10, Kind: model.DeadLetterStatus, Stage: model.StagePersist}, []int64{status.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dls, err := r.ListDeadLetters(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("ListDeadLetters: %v", err)
+			}
+			if got := deadLetterIDs(dls); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetDeadLetter(t *testing.T) {
+	r := newTestRepository(t)
+	saved := &model.DeadLetter{Kind: model.DeadLetterRefund, Channel: "order-refunds", Payload: `{"refund_id":"r1"}`, Sequence: 42, Stage: model.StageValidate, Error: "bad refund"}
+	saveDeadLetters(t, r, saved)
+
+	dl, err := r.GetDeadLetter(context.Background(), saved.ID)
+	if err != nil {
+		t.Fatalf("GetDeadLetter: %v", err)
+	}
+	if dl.Kind != saved.Kind || dl.Channel != saved.Channel || dl.Payload != saved.Payload ||
+		dl.Sequence != 42 || dl.Stage != saved.Stage || dl.Error != saved.Error || dl.ReplayedAt != nil {
+		t.Errorf("Expected %+v, got %+v", saved, dl)
+	}
+
+	if _, err := r.GetDeadLetter(context.Background(), saved.ID+1); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected ErrDeadLetterNotFound, got %v", err)
+	}
+}
+
+func TestDeadLetterReplayUpdates(t *testing.T) {
+	r := newTestRepository(t)
+	saved := &model.DeadLetter{Payload: `{}`, Stage: model.StageDecode, Error: "unexpected end of JSON input"}
+	saveDeadLetters(t, r, saved)
+	ctx := context.Background()
+
+	if err := r.UpdateDeadLetterError(ctx, saved.ID, model.StagePersist, "connection refused"); err != nil {
+		t.Fatalf("UpdateDeadLetterError: %v", err)
+	}
+	if err := r.MarkDeadLetterReplayed(ctx, saved.ID); err != nil {
+		t.Fatalf("MarkDeadLetterReplayed: %v", err)
+	}
+	dl, err := r.GetDeadLetter(ctx, saved.ID)
+	if err != nil {
+		t.Fatalf("GetDeadLetter: %v", err)
+	}
+	if dl.Stage != model.StagePersist || dl.Error != "connection refused" || dl.ReplayedAt == nil {
+		t.Errorf("Expected a replayed persist failure, got %+v", dl)
+	}
+
+	if err := r.MarkDeadLetterReplayed(ctx, saved.ID+1); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected ErrDeadLetterNotFound, got %v", err)
+	}
+	if err := r.UpdateDeadLetterError(ctx, saved.ID+1, model.StagePersist, ""); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected ErrDeadLetterNotFound, got %v", err)
+	}
+}
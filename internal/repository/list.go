@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderFilter narrows ListOrders. Zero values match everything.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+	PaymentProvider string
+	Currency        string
+	Brand           string
+}
+
+type OrderPage struct {
+	Orders     []*model.Order `json:"orders"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Total      int            `json:"total"`
+}
+
+type OrderLister interface {
+	ListOrders(ctx context.Context, filter OrderFilter, cursor string, limit int) (*OrderPage, error)
+}
+
+// ListOrders returns one page of orders matching filter, newest first. The
+// cursor is the NextCursor of the previous page, empty for the first one.
+// Total counts every matching order, not just the page.
+func (r *PostgresRepository) ListOrders(ctx context.Context, filter OrderFilter, cursor string, limit int) (*OrderPage, error) {
+	defer metrics.ObserveQuery("ListOrders")()
+
+	where := filterConditions(filter)
+
+	page := &OrderPage{Orders: []*model.Order{}}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM orders o
+		JOIN payment p ON p.order_uid = o.order_uid
+	`+where.sql(), where.args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		createdAt, orderUID, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		where.add("(o.date_created, o.order_uid) < (%s, %s)", createdAt, orderUID)
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	orders, err := r.queryOrders(ctx, where, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[len(orders)-1]
+		page.NextCursor = encodeCursor(last.DateCreated, last.OrderUID)
+	}
+	if orders != nil {
+		page.Orders = orders
+	}
+	return page, nil
+}
+
+func filterConditions(f OrderFilter) conditions {
+	var where conditions
+	if f.CustomerID != "" {
+		where.add("o.customer_id = %s", f.CustomerID)
+	}
+	if f.TrackNumber != "" {
+		where.add("o.track_number = %s", f.TrackNumber)
+	}
+	if f.DeliveryService != "" {
+		where.add("o.delivery_service = %s", f.DeliveryService)
+	}
+	if !f.CreatedFrom.IsZero() {
+		where.add("o.date_created >= %s", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		where.add("o.date_created < %s", f.CreatedTo)
+	}
+	if f.PaymentProvider != "" {
+		where.add("p.provider = %s", f.PaymentProvider)
+	}
+	if f.Currency != "" {
+		where.add("p.currency = %s", f.Currency)
+	}
+	if f.Brand != "" {
+		where.add("EXISTS (SELECT 1 FROM items i WHERE i.order_uid = o.order_uid AND i.brand = %s)", f.Brand)
+	}
+	return where
+}
+
+// conditions accumulates AND-ed SQL predicates with numbered placeholders.
+type conditions struct {
+	clauses []string
+	args    []any
+}
+
+// add appends a predicate whose %s verbs are replaced by placeholders for args.
+func (c *conditions) add(format string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, arg := range args {
+		c.args = append(c.args, arg)
+		placeholders[i] = fmt.Sprintf("$%d", len(c.args))
+	}
+	c.clauses = append(c.clauses, fmt.Sprintf(format, placeholders...))
+}
+
+func (c conditions) sql() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+func encodeCursor(createdAt time.Time, orderUID string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + orderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	ts, orderUID, ok := strings.Cut(string(raw), "|")
+	if !ok || orderUID == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return createdAt, orderUID, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type OutboxStore interface {
+	PendingEvents(ctx context.Context, limit int) ([]*model.OutboxEvent, error)
+	DeleteEvents(ctx context.Context, ids []int64) error
+}
+
+// SetOutbox makes CreateOrder and CreateOrders write an order.accepted event
+// to the outbox in the transaction that stores a new order.
+func (r *PostgresRepository) SetOutbox(enabled bool) {
+	r.outbox = enabled
+}
+
+func insertOrderAccepted(ctx context.Context, tx *sql.Tx, order *model.Order) error {
+	event, err := model.NewOrderAcceptedEvent(order)
+	if err != nil {
+		return err
+	}
+	// Payload goes as text: pq would send []byte in binary, which jsonb rejects.
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox (event_id, event_type, order_uid, payload)
+		VALUES ($1, $2, $3, $4)
+	`, event.EventID, event.Type, event.OrderUID, string(event.Payload))
+	return err
+}
+
+// PendingEvents returns up to limit unpublished events, oldest first.
+func (r *PostgresRepository) PendingEvents(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	defer metrics.ObserveQuery("PendingEvents")()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, event_id, event_type, order_uid, payload, created_at
+		FROM outbox ORDER BY id LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.OutboxEvent
+	for rows.Next() {
+		var e model.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.EventID, &e.Type, &e.OrderUID, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteEvents removes published events from the outbox.
+func (r *PostgresRepository) DeleteEvents(ctx context.Context, ids []int64) error {
+	defer metrics.ObserveQuery("DeleteEvents")()
+
+	_, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = ANY($1)", pq.Array(ids))
+	return err
+}
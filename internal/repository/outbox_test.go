@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCreateOrder_FailedWriteLeavesNoOutboxEvent(t *testing.T) {
+	r := newTestRepository(t)
+	r.SetOutbox(true)
+	poisoned := testOrder("poison")
+	poisoned.Items[1].Size = strings.Repeat("X", 11)
+
+	if err := r.CreateOrder(context.Background(), poisoned); err == nil {
+		t.Fatal("Expected the oversized item to fail the write")
+	}
+	if got := countRows(t, r, "outbox"); got != 0 {
+		t.Errorf("Expected no outbox event for the rolled back order, got %d", got)
+	}
+
+	if err := r.CreateOrder(context.Background(), testOrder("a")); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := r.CreateOrder(context.Background(), testOrder("a")); err == nil {
+		t.Fatal("Expected the redelivered order to be a duplicate")
+	}
+	events, err := r.PendingEvents(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("PendingEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].OrderUID != "a" {
+		t.Errorf("Expected a single event for order a, got %v", events)
+	}
+}
@@ -3,11 +3,36 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/metrics"
 	"order-service/internal/model"
+	"reflect"
+	"slices"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+var (
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrDuplicateOrder = errors.New("order already stored")
+	ErrOrderConflict  = errors.New("order already stored with different data")
+	ErrStop           = errors.New("stop streaming")
+)
+
+// ConflictPolicy decides what CreateOrder does with an order whose UID is
+// already stored with different data.
+type ConflictPolicy string
+
+const (
+	ConflictReject ConflictPolicy = "reject"
+	ConflictUpdate ConflictPolicy = "update"
+)
+
+const DefaultPageSize = 1000
+
 type OrderRepository interface {
 	CreateOrder(ctx context.Context, order *model.Order) error
 	GetOrderByUID(ctx context.Context, orderUID string) (*model.Order, error)
@@ -15,7 +40,9 @@ type OrderRepository interface {
 }
 
 type PostgresRepository struct {
-	db *sql.DB
+	db             *sql.DB
+	conflictPolicy ConflictPolicy
+	outbox         bool
 }
 
 func NewPostgresRepository(connStr string) (*PostgresRepository, error) {
@@ -28,29 +55,96 @@ func NewPostgresRepository(connStr string) (*PostgresRepository, error) {
 		return nil, err
 	}
 
-	return &PostgresRepository{db: db}, nil
+	return &PostgresRepository{db: db, conflictPolicy: ConflictReject}, nil
+}
+
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *PostgresRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *PostgresRepository) SetConflictPolicy(policy ConflictPolicy) {
+	r.conflictPolicy = policy
 }
 
+// CreateOrder stores an order idempotently. A redelivered order identical to
+// the stored one yields ErrDuplicateOrder; a differing one is handled
+// according to the repository's ConflictPolicy. A rejected order is only
+// reported as ErrOrderConflict once it is recorded in order_conflicts;
+// failing that, the error is a plain one, so the message is retried rather
+// than dropped.
 func (r *PostgresRepository) CreateOrder(ctx context.Context, order *model.Order) error {
+	defer metrics.ObserveQuery("CreateOrder")()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, 
+	if err := r.saveOrder(ctx, tx, order); err != nil {
+		if errors.Is(err, ErrOrderConflict) {
+			tx.Rollback()
+			if logErr := r.logConflict(ctx, order); logErr != nil {
+				return fmt.Errorf("record conflicting order %s: %w", order.OrderUID, logErr)
+			}
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// saveOrder writes order and its parts. The order.accepted event of a new
+// order goes last, so that it carries the order as stored.
+func (r *PostgresRepository) saveOrder(ctx context.Context, tx *sql.Tx, order *model.Order) error {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature,
 		                   customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (order_uid) DO NOTHING
 	`, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
 		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard)
 	if err != nil {
 		return err
 	}
 
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		existing, err := r.getOrder(ctx, tx, order.OrderUID, true)
+		if err != nil {
+			return err
+		}
+		copyServerFields(order, existing)
+		if sameOrder(existing, order) {
+			return ErrDuplicateOrder
+		}
+		if r.conflictPolicy != ConflictUpdate {
+			return ErrOrderConflict
+		}
+		if err := updateOrder(ctx, tx, order); err != nil {
+			return err
+		}
+	} else {
+		change := model.StatusChange{Status: model.StatusCreated}
+		if err := insertStatusChange(ctx, tx, order.OrderUID, &change); err != nil {
+			return err
+		}
+		initServerFields(order, change)
+	}
+
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (order_uid) DO UPDATE SET
+			name = EXCLUDED.name, phone = EXCLUDED.phone, zip = EXCLUDED.zip, city = EXCLUDED.city,
+			address = EXCLUDED.address, region = EXCLUDED.region, email = EXCLUDED.email
 	`, order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip,
 		order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email)
 	if err != nil {
@@ -58,9 +152,14 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *model.Order
 	}
 
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO payment (order_uid, transaction, request_id, currency, provider, 
+		INSERT INTO payment (order_uid, transaction, request_id, currency, provider,
 		                   amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (order_uid) DO UPDATE SET
+			transaction = EXCLUDED.transaction, request_id = EXCLUDED.request_id,
+			currency = EXCLUDED.currency, provider = EXCLUDED.provider, amount = EXCLUDED.amount,
+			payment_dt = EXCLUDED.payment_dt, bank = EXCLUDED.bank, delivery_cost = EXCLUDED.delivery_cost,
+			goods_total = EXCLUDED.goods_total, custom_fee = EXCLUDED.custom_fee
 	`, order.OrderUID, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency,
 		order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank,
 		order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee)
@@ -68,9 +167,15 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *model.Order
 		return err
 	}
 
+	if inserted == 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM items WHERE order_uid = $1", order.OrderUID); err != nil {
+			return err
+		}
+	}
+
 	for _, item := range order.Items {
 		_, err = tx.ExecContext(ctx, `
-			INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, 
+			INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name,
 			                   sale, size, total_price, nm_id, brand, status)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		`, order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
@@ -80,25 +185,114 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *model.Order
 		}
 	}
 
-	return tx.Commit()
+	if inserted != 0 && r.outbox {
+		return insertOrderAccepted(ctx, tx, order)
+	}
+	return nil
+}
+
+func updateOrder(ctx context.Context, tx *sql.Tx, order *model.Order) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE orders SET track_number = $2, entry = $3, locale = $4, internal_signature = $5,
+		       customer_id = $6, delivery_service = $7, shardkey = $8, sm_id = $9,
+		       date_created = $10, oof_shard = $11
+		WHERE order_uid = $1
+	`, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard)
+	return err
+}
+
+func (r *PostgresRepository) logConflict(ctx context.Context, order *model.Order) error {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO order_conflicts (order_uid, payload) VALUES ($1, $2)
+	`, order.OrderUID, payload)
+	return err
+}
+
+// sameOrder compares two orders the way they round-trip through Postgres:
+// timestamps at microsecond precision and without the nested order_uid copies.
+func sameOrder(a, b *model.Order) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+func normalize(o *model.Order) model.Order {
+	n := *o
+	n.DateCreated = o.DateCreated.UTC().Truncate(time.Microsecond)
+	n.Status, n.StatusHistory = "", nil
+	n.Delivery.OrderUID = ""
+	n.Payment.OrderUID = ""
+	n.Payment.Refunds, n.Payment.Refunded = nil, 0
+	n.Items = make([]model.Item, len(o.Items))
+	for i, item := range o.Items {
+		item.OrderUID = ""
+		item.Refunded = false
+		n.Items[i] = item
+	}
+	return n
+}
+
+// initServerFields gives a newly stored order its first status and drops any
+// refunds claimed by the incoming copy, which only the service records.
+func initServerFields(o *model.Order, created model.StatusChange) {
+	copyServerFields(o, &model.Order{Status: model.StatusCreated, StatusHistory: []model.StatusChange{created}})
+}
+
+// copyServerFields carries the status and refunds of the stored order over to
+// an incoming copy of it, which never has them.
+func copyServerFields(dst, stored *model.Order) {
+	dst.Status, dst.StatusHistory = stored.Status, stored.StatusHistory
+	dst.Payment.Refunds, dst.Payment.Refunded = stored.Payment.Refunds, stored.Payment.Refunded
+
+	refunded := make(map[string]bool)
+	for _, item := range stored.Items {
+		if item.Refunded {
+			refunded[item.Rid] = true
+		}
+	}
+	for i := range dst.Items {
+		dst.Items[i].Refunded = refunded[dst.Items[i].Rid]
+	}
 }
 
 func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string) (*model.Order, error) {
+	defer metrics.ObserveQuery("GetOrderByUID")()
+
+	return r.getOrder(ctx, r.db, orderUID, false)
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (r *PostgresRepository) getOrder(ctx context.Context, q querier, orderUID string, forUpdate bool) (*model.Order, error) {
 	var order model.Order
-	
-	err := r.db.QueryRowContext(ctx, `
-		SELECT order_uid, track_number, entry, locale, internal_signature, 
-		       customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
+
+	query := `
+		SELECT order_uid, track_number, entry, locale, internal_signature,
+		       customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status
 		FROM orders WHERE order_uid = $1
-	`, orderUID).Scan(
+	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	err := q.QueryRowContext(ctx, query, orderUID).Scan(
 		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
 		&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+		&order.Status,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = r.db.QueryRowContext(ctx, `
+	err = q.QueryRowContext(ctx, `
 		SELECT name, phone, zip, city, address, region, email
 		FROM delivery WHERE order_uid = $1
 	`, orderUID).Scan(
@@ -109,7 +303,7 @@ func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string)
 		return nil, err
 	}
 
-	err = r.db.QueryRowContext(ctx, `
+	err = q.QueryRowContext(ctx, `
 		SELECT transaction, request_id, currency, provider, amount, payment_dt, 
 		       bank, delivery_cost, goods_total, custom_fee
 		FROM payment WHERE order_uid = $1
@@ -122,9 +316,10 @@ func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string)
 		return nil, err
 	}
 
-	rows, err := r.db.QueryContext(ctx, `
+	rows, err := q.QueryContext(ctx, `
 		SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
 		FROM items WHERE order_uid = $1
+		ORDER BY id
 	`, orderUID)
 	if err != nil {
 		return nil, err
@@ -145,29 +340,169 @@ func (r *PostgresRepository) GetOrderByUID(ctx context.Context, orderUID string)
 	}
 	order.Items = items
 
+	byUID := map[string]*model.Order{orderUID: &order}
+	if err := loadStatusHistory(ctx, q, []string{orderUID}, byUID); err != nil {
+		return nil, err
+	}
+	if err := loadRefunds(ctx, q, []string{orderUID}, byUID); err != nil {
+		return nil, err
+	}
 	return &order, nil
 }
 
 func (r *PostgresRepository) GetAllOrders(ctx context.Context) ([]*model.Order, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT order_uid FROM orders")
+	var orders []*model.Order
+	err := r.StreamOrders(ctx, DefaultPageSize, func(page []*model.Order) error {
+		orders = append(orders, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+const pageQuery = `
+	SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+	       o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
+	       d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+	       p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt,
+	       p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+	FROM orders o
+	JOIN delivery d ON d.order_uid = o.order_uid
+	JOIN payment p ON p.order_uid = o.order_uid
+`
+
+func (r *PostgresRepository) CountOrders(ctx context.Context) (int, error) {
+	defer metrics.ObserveQuery("CountOrders")()
+
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT count(*) FROM orders").Scan(&count)
+	return count, err
+}
+
+// StreamOrders walks all orders from newest to oldest in pages of pageSize,
+// loading each page with one query for orders, delivery and payment and one
+// query for their items. Returning an error from fn stops the walk; ErrStop
+// stops it without an error.
+func (r *PostgresRepository) StreamOrders(ctx context.Context, pageSize int, fn func(page []*model.Order) error) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	var last *model.Order
+	for {
+		page, err := r.loadPage(ctx, last, pageSize)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+
+		if err := fn(page); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+
+		if len(page) < pageSize {
+			return nil
+		}
+		last = page[len(page)-1]
+	}
+}
+
+func (r *PostgresRepository) loadPage(ctx context.Context, after *model.Order, limit int) ([]*model.Order, error) {
+	defer metrics.ObserveQuery("StreamOrders")()
+
+	var where conditions
+	if after != nil {
+		where.add("(o.date_created, o.order_uid) < (%s, %s)", after.DateCreated, after.OrderUID)
+	}
+	return r.queryOrders(ctx, where, limit)
+}
+
+// queryOrders loads up to limit orders matching where, newest first, with
+// their delivery, payment and items.
+func (r *PostgresRepository) queryOrders(ctx context.Context, where conditions, limit int) ([]*model.Order, error) {
+	args := append(slices.Clip(where.args), limit)
+	rows, err := r.db.QueryContext(ctx, pageQuery+where.sql()+fmt.Sprintf(`
+		ORDER BY o.date_created DESC, o.order_uid DESC
+		LIMIT $%d
+	`, len(args)), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []*model.Order
+	var (
+		orders []*model.Order
+		uids   []string
+		byUID  = make(map[string]*model.Order)
+	)
 	for rows.Next() {
-		var orderUID string
-		if err := rows.Scan(&orderUID); err != nil {
-			return nil, err
-		}
-		
-		order, err := r.GetOrderByUID(ctx, orderUID)
+		var order model.Order
+		err := rows.Scan(
+			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+			&order.Status,
+			&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City,
+			&order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+			&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider,
+			&order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost,
+			&order.Payment.GoodsTotal, &order.Payment.CustomFee,
+		)
 		if err != nil {
 			return nil, err
 		}
-		orders = append(orders, order)
+		orders = append(orders, &order)
+		uids = append(uids, order.OrderUID)
+		byUID[order.OrderUID] = &order
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
 	}
 
+	if err := r.loadItems(ctx, uids, byUID); err != nil {
+		return nil, err
+	}
+	if err := loadStatusHistory(ctx, r.db, uids, byUID); err != nil {
+		return nil, err
+	}
+	if err := loadRefunds(ctx, r.db, uids, byUID); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
+
+func (r *PostgresRepository) loadItems(ctx context.Context, uids []string, byUID map[string]*model.Order) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+		FROM items WHERE order_uid = ANY($1)
+		ORDER BY order_uid, id
+	`, pq.Array(uids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item model.Item
+		err := rows.Scan(
+			&item.OrderUID, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale,
+			&item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status,
+		)
+		if err != nil {
+			return err
+		}
+		if order, ok := byUID[item.OrderUID]; ok {
+			order.Items = append(order.Items, item)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/model"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSameOrder(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.FixedZone("MSK", 3*60*60))
+
+	incoming := &model.Order{
+		OrderUID:    "test123",
+		TrackNumber: "TRACK123",
+		DateCreated: created,
+		Delivery:    model.Delivery{Name: "Test User"},
+		Payment:     model.Payment{Transaction: "test123", Amount: 100},
+		Items:       []model.Item{{Name: "Item", Rid: "rid1", TotalPrice: 100}},
+	}
+
+	stored := *incoming
+	stored.DateCreated = created.UTC().Truncate(time.Microsecond)
+	stored.Delivery.OrderUID = "test123"
+	stored.Payment.OrderUID = "test123"
+	stored.Items = []model.Item{{OrderUID: "test123", Name: "Item", Rid: "rid1", TotalPrice: 100}}
+
+	if !sameOrder(&stored, incoming) {
+		t.Error("Expected stored order to match redelivered payload")
+	}
+
+	changed := *incoming
+	changed.Items = []model.Item{{Name: "Item", Rid: "rid1", TotalPrice: 90}}
+	if sameOrder(&stored, &changed) {
+		t.Error("Expected orders with different items to differ")
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2021, 11, 26, 6, 22, 19, 123456000, time.UTC)
+
+	gotTime, gotUID, err := decodeCursor(encodeCursor(created, "b563feb7b2b84b6test"))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if !gotTime.Equal(created) || gotUID != "b563feb7b2b84b6test" {
+		t.Errorf("Expected (%v, b563feb7b2b84b6test), got (%v, %s)", created, gotTime, gotUID)
+	}
+
+	for _, cursor := range []string{"!!!", encodeCursor(created, "")[:4], "bm8tc2VwYXJhdG9y"} {
+		if _, _, err := decodeCursor(cursor); err != ErrInvalidCursor {
+			t.Errorf("decodeCursor(%q): expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
+func TestFilterConditions(t *testing.T) {
+	where := filterConditions(OrderFilter{CustomerID: "test", Currency: "USD", Brand: "Vivienne Sabo"})
+
+	want := " WHERE o.customer_id = $1 AND p.currency = $2 AND " +
+		"EXISTS (SELECT 1 FROM items i WHERE i.order_uid = o.order_uid AND i.brand = $3)"
+	if got := where.sql(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if len(where.args) != 3 {
+		t.Errorf("Expected 3 args, got %d", len(where.args))
+	}
+}
+
+func TestApplyRefundFlagsItems(t *testing.T) {
+	order := &model.Order{
+		OrderUID: "test123",
+		Payment:  model.Payment{Amount: 1817},
+		Items:    []model.Item{{Rid: "rid1", TotalPrice: 317}, {Rid: "rid2", TotalPrice: 1500}},
+	}
+
+	applyRefund(order, model.Refund{RefundID: "r1", Amount: 317, Rids: []string{"rid1"}})
+	applyRefund(order, model.Refund{RefundID: "r2", Amount: 100})
+
+	if order.Payment.Refunded != 417 || len(order.Payment.Refunds) != 2 {
+		t.Errorf("Expected 417 refunded in 2 refunds, got %d in %d", order.Payment.Refunded, len(order.Payment.Refunds))
+	}
+	if !order.Items[0].Refunded || order.Items[1].Refunded {
+		t.Errorf("Expected only rid1 to be refunded, got %+v", order.Items)
+	}
+
+	incoming := &model.Order{
+		OrderUID: "test123",
+		Payment:  model.Payment{Amount: 1817},
+		Items:    []model.Item{{Rid: "rid1", TotalPrice: 317}, {Rid: "rid2", TotalPrice: 1500}},
+	}
+	copyServerFields(incoming, order)
+	if !sameOrder(order, incoming) || !incoming.Items[0].Refunded || incoming.Payment.Refunded != 417 {
+		t.Errorf("Expected redelivered order to pick up stored refunds, got %+v", incoming)
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	orders := []*model.Order{{OrderUID: "a"}, {OrderUID: "stored"}, {OrderUID: "b"}, {OrderUID: "a"}}
+
+	batch, single := splitBatch(orders, map[string]bool{"stored": true})
+	if !slices.Equal(batch, []int{0, 2}) || !slices.Equal(single, []int{1, 3}) {
+		t.Errorf("Expected batch [0 2] and single [1 3], got %v and %v", batch, single)
+	}
+}
+
+func TestCreateOrder_UnrecordedConflictIsNotAConflict(t *testing.T) {
+	r := newTestRepository(t)
+	if err := r.CreateOrder(context.Background(), testOrder("a")); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := r.db.Exec("DROP TABLE order_conflicts"); err != nil {
+		t.Fatalf("drop order_conflicts: %v", err)
+	}
+
+	changed := testOrder("a")
+	changed.TrackNumber = "CHANGED"
+	err := r.CreateOrder(context.Background(), changed)
+	if err == nil || errors.Is(err, ErrOrderConflict) {
+		t.Errorf("Expected a retryable error without the conflict record, got %v", err)
+	}
+}
+
+func TestCreateOrder_DuplicateIsNoOp(t *testing.T) {
+	r := newTestRepository(t)
+	r.SetOutbox(true)
+	storeTestOrder(t, r, "a")
+
+	if err := r.CreateOrder(context.Background(), testOrder("a")); !errors.Is(err, ErrDuplicateOrder) {
+		t.Fatalf("Expected ErrDuplicateOrder, got %v", err)
+	}
+	want := map[string]int{"orders": 1, "items": 2, "order_status_history": 1, "outbox": 1, "order_conflicts": 0}
+	for table, n := range want {
+		if got := countRows(t, r, table); got != n {
+			t.Errorf("Expected %d rows in %s, got %d", n, table, got)
+		}
+	}
+}
+
+func TestCreateOrder_RejectRecordsConflict(t *testing.T) {
+	r := newTestRepository(t)
+	storeTestOrder(t, r, "a")
+
+	changed := testOrder("a")
+	changed.TrackNumber = "CHANGED"
+	if err := r.CreateOrder(context.Background(), changed); !errors.Is(err, ErrOrderConflict) {
+		t.Fatalf("Expected ErrOrderConflict, got %v", err)
+	}
+
+	var uid, track string
+	err := r.db.QueryRow("SELECT order_uid, payload->>'track_number' FROM order_conflicts").Scan(&uid, &track)
+	if err != nil {
+		t.Fatalf("read order_conflicts: %v", err)
+	}
+	if uid != "a" || track != "CHANGED" {
+		t.Errorf("Expected the rejected payload of a, got %s with track %s", uid, track)
+	}
+	stored, err := r.GetOrderByUID(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	if !sameOrder(stored, testOrder("a")) {
+		t.Error("Expected the stored order to be left untouched")
+	}
+}
+
+func TestCreateOrder_UpdateKeepsServerFields(t *testing.T) {
+	r := newTestRepository(t)
+	r.SetConflictPolicy(ConflictUpdate)
+	original := storeTestOrder(t, r, "a")
+	ctx := context.Background()
+	kept := original.Items[0].Rid
+	if _, err := r.UpdateOrderStatus(ctx, &model.StatusEvent{OrderUID: "a", Status: model.StatusPaid}); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+	if _, err := r.RefundOrder(ctx, &model.RefundEvent{RefundID: "r1", OrderUID: "a", Type: model.RefundTypeRefund, Rids: []string{kept}}); err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+
+	// A failing update leaves the old items in place.
+	poisoned := testOrder("a")
+	poisoned.Items = append(poisoned.Items[:1], model.Item{Rid: "new", Size: "XXXXXXXXXXX"})
+	if err := r.CreateOrder(ctx, poisoned); err == nil {
+		t.Fatal("Expected the oversized item to fail the update")
+	}
+	if got := countRows(t, r, "items"); got != 2 {
+		t.Errorf("Expected the 2 original items after a failed update, got %d", got)
+	}
+
+	changed := testOrder("a")
+	changed.TrackNumber = "CHANGED"
+	changed.Items = append(changed.Items[:1], model.Item{Rid: "new", Name: "Comb", TotalPrice: 50, Brand: "Vivienne Sabo"})
+	if err := r.CreateOrder(ctx, changed); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	stored, err := r.GetOrderByUID(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	var rids []string
+	for _, item := range stored.Items {
+		rids = append(rids, item.Rid)
+	}
+	if stored.TrackNumber != "CHANGED" || !slices.Equal(rids, []string{kept, "new"}) {
+		t.Errorf("Expected the update with items [%s new], got %s with %v", kept, stored.TrackNumber, rids)
+	}
+	for _, order := range []*model.Order{stored, changed} {
+		if order.Status != model.StatusPaid || order.Payment.Refunded != original.Items[0].TotalPrice || !order.Items[0].Refunded {
+			t.Errorf("Expected status and refunds to survive the update, got %q refunding %d", order.Status, order.Payment.Refunded)
+		}
+	}
+}
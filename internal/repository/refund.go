@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateRefund     = errors.New("refund already recorded")
+	ErrRefundExceedsAmount = errors.New("refund exceeds the unrefunded payment amount")
+	ErrUnknownItem         = errors.New("item is not part of the order")
+	ErrItemAlreadyRefunded = errors.New("item already refunded")
+)
+
+type RefundStore interface {
+	RefundOrder(ctx context.Context, event *model.RefundEvent) (*model.Order, error)
+}
+
+// RefundOrder records a refund or cancellation and returns the updated order.
+// Refunds of one order are serialised on its rows, so their sum never exceeds
+// payment.amount. The order row is locked before the payment row, the same
+// order saveOrder uses, so that a refund cannot deadlock with a redelivered
+// update of the order.
+func (r *PostgresRepository) RefundOrder(ctx context.Context, event *model.RefundEvent) (*model.Order, error) {
+	defer metrics.ObserveQuery("RefundOrder")()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current model.OrderStatus
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM orders WHERE order_uid = $1 FOR UPDATE
+	`, event.OrderUID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paid, refunded int
+	err = tx.QueryRowContext(ctx, `
+		SELECT amount FROM payment WHERE order_uid = $1 FOR UPDATE
+	`, event.OrderUID).Scan(&paid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM refunds WHERE refund_id = $1),
+		       (SELECT COALESCE(sum(amount), 0) FROM refunds WHERE order_uid = $2)
+	`, event.RefundID, event.OrderUID).Scan(&exists, &refunded)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrDuplicateRefund
+	}
+	remaining := paid - refunded
+
+	amount := event.Amount
+	switch {
+	case event.Type == model.RefundTypeCancel:
+		if err := cancelOrder(ctx, tx, current, event); err != nil {
+			return nil, err
+		}
+		amount = remaining
+	case len(event.Rids) > 0:
+		itemsTotal, err := refundableItems(ctx, tx, event.OrderUID, event.Rids)
+		if err != nil {
+			return nil, err
+		}
+		if amount == 0 {
+			amount = itemsTotal
+		}
+	case amount == 0:
+		amount = remaining
+	}
+
+	if amount > remaining || (amount == 0 && event.Type != model.RefundTypeCancel) {
+		return nil, fmt.Errorf("%w: %d requested, %d of %d left", ErrRefundExceedsAmount, amount, remaining, paid)
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refunds (refund_id, order_uid, amount, reason, created_at)
+		VALUES ($1, $2, $3, $4, COALESCE($5, now()))
+		RETURNING id
+	`, event.RefundID, event.OrderUID, amount, event.Reason, nullTime(event.OccurredAt)).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	for _, rid := range event.Rids {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO refund_items (refund_id, order_uid, rid) VALUES ($1, $2, $3)
+		`, id, event.OrderUID, rid); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := r.getOrder(ctx, tx, event.OrderUID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// cancelOrder moves an order locked by the caller from current to cancelled.
+func cancelOrder(ctx context.Context, tx *sql.Tx, current model.OrderStatus, event *model.RefundEvent) error {
+	if !current.CanTransition(model.StatusCancelled) {
+		return &model.TransitionError{From: current, To: model.StatusCancelled}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = $2 WHERE order_uid = $1
+	`, event.OrderUID, model.StatusCancelled); err != nil {
+		return err
+	}
+	change := model.StatusChange{Status: model.StatusCancelled, Reason: event.Reason, ChangedAt: event.OccurredAt}
+	return insertStatusChange(ctx, tx, event.OrderUID, &change)
+}
+
+// refundableItems checks that every rid belongs to the order and has not been
+// refunded yet, and returns their total price.
+func refundableItems(ctx context.Context, tx *sql.Tx, orderUID string, rids []string) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT i.rid, i.total_price, ri.rid IS NOT NULL
+		FROM items i
+		LEFT JOIN refund_items ri ON ri.order_uid = i.order_uid AND ri.rid = i.rid
+		WHERE i.order_uid = $1 AND i.rid = ANY($2)
+	`, orderUID, pq.Array(rids))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool, len(rids))
+	total := 0
+	for rows.Next() {
+		var rid string
+		var price int
+		var refunded bool
+		if err := rows.Scan(&rid, &price, &refunded); err != nil {
+			return 0, err
+		}
+		if refunded {
+			return 0, fmt.Errorf("%w: %s", ErrItemAlreadyRefunded, rid)
+		}
+		found[rid] = true
+		total += price
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, rid := range rids {
+		if !found[rid] {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownItem, rid)
+		}
+	}
+	return total, nil
+}
+
+func loadRefunds(ctx context.Context, q querier, uids []string, byUID map[string]*model.Order) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT r.order_uid, r.refund_id, r.amount, r.reason, r.created_at,
+		       array_remove(array_agg(ri.rid ORDER BY ri.rid), NULL)
+		FROM refunds r
+		LEFT JOIN refund_items ri ON ri.refund_id = r.id
+		WHERE r.order_uid = ANY($1)
+		GROUP BY r.id
+		ORDER BY r.order_uid, r.id
+	`, pq.Array(uids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderUID string
+		var refund model.Refund
+		err := rows.Scan(&orderUID, &refund.RefundID, &refund.Amount, &refund.Reason, &refund.CreatedAt,
+			pq.Array(&refund.Rids))
+		if err != nil {
+			return err
+		}
+		if order, ok := byUID[orderUID]; ok {
+			applyRefund(order, refund)
+		}
+	}
+	return rows.Err()
+}
+
+// applyRefund adds refund to the order's payment and flags the refunded items.
+func applyRefund(order *model.Order, refund model.Refund) {
+	order.Payment.Refunds = append(order.Payment.Refunds, refund)
+	order.Payment.Refunded += refund.Amount
+	for _, rid := range refund.Rids {
+		for i := range order.Items {
+			if order.Items[i].Rid == rid {
+				order.Items[i].Refunded = true
+			}
+		}
+	}
+}
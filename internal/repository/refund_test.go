@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// forgedRefunds adds to order the refunds a publisher might try to slip in.
+func forgedRefunds(order *model.Order) *model.Order {
+	order.Payment.Refunds = []model.Refund{{RefundID: "forged", Amount: order.Payment.Amount}}
+	order.Payment.Refunded = order.Payment.Amount
+	order.Items[0].Refunded = true
+	order.Status = model.StatusCancelled
+	return order
+}
+
+func assertNoRefunds(t *testing.T, order *model.Order) {
+	t.Helper()
+	if len(order.Payment.Refunds) != 0 || order.Payment.Refunded != 0 {
+		t.Errorf("Expected no refunds, got %v refunding %d", order.Payment.Refunds, order.Payment.Refunded)
+	}
+	for _, item := range order.Items {
+		if item.Refunded {
+			t.Errorf("Expected item %s not to be refunded", item.Rid)
+		}
+	}
+	if order.Status != model.StatusCreated {
+		t.Errorf("Expected status %q, got %q", model.StatusCreated, order.Status)
+	}
+}
+
+func TestInitServerFields(t *testing.T) {
+	order := forgedRefunds(testOrder("a"))
+	created := model.StatusChange{Status: model.StatusCreated, ChangedAt: time.Now()}
+
+	initServerFields(order, created)
+
+	assertNoRefunds(t, order)
+	if len(order.StatusHistory) != 1 || order.StatusHistory[0] != created {
+		t.Errorf("Expected the created status as the only change, got %v", order.StatusHistory)
+	}
+}
+
+func TestCreateOrder_DropsIncomingRefunds(t *testing.T) {
+	r := newTestRepository(t)
+	r.SetOutbox(true)
+
+	single := forgedRefunds(testOrder("a"))
+	if err := r.CreateOrder(context.Background(), single); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	batched := forgedRefunds(testOrder("b"))
+	if errs := r.CreateOrders(context.Background(), []*model.Order{batched}); errs[0] != nil {
+		t.Fatalf("CreateOrders: %v", errs[0])
+	}
+
+	for _, order := range []*model.Order{single, batched} {
+		assertNoRefunds(t, order)
+		stored, err := r.GetOrderByUID(context.Background(), order.OrderUID)
+		if err != nil {
+			t.Fatalf("GetOrderByUID: %v", err)
+		}
+		assertNoRefunds(t, stored)
+	}
+
+	events, err := r.PendingEvents(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("PendingEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 outbox events, got %d", len(events))
+	}
+	for _, event := range events {
+		var accepted model.OrderAccepted
+		if err := json.Unmarshal(event.Payload, &accepted); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		assertNoRefunds(t, accepted.Order)
+	}
+}
+
+// storeTestOrder stores testOrder(uid): paid 1817, items for 317 and 100.
+func storeTestOrder(t *testing.T, r *PostgresRepository, uid string) *model.Order {
+	t.Helper()
+	order := testOrder(uid)
+	if err := r.CreateOrder(context.Background(), order); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	return order
+}
+
+func refund(id, uid string, amount int, rids ...string) *model.RefundEvent {
+	return &model.RefundEvent{RefundID: id, OrderUID: uid, Type: model.RefundTypeRefund, Amount: amount, Rids: rids}
+}
+
+func TestRefundOrder_DuplicateIsNoOp(t *testing.T) {
+	r := newTestRepository(t)
+	storeTestOrder(t, r, "a")
+	ctx := context.Background()
+
+	if _, err := r.RefundOrder(ctx, refund("r1", "a", 500)); err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	if _, err := r.RefundOrder(ctx, refund("r1", "a", 500)); !errors.Is(err, ErrDuplicateRefund) {
+		t.Errorf("Expected ErrDuplicateRefund, got %v", err)
+	}
+
+	stored, err := r.GetOrderByUID(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	if stored.Payment.Refunded != 500 || len(stored.Payment.Refunds) != 1 {
+		t.Errorf("Expected one refund of 500, got %v", stored.Payment.Refunds)
+	}
+}
+
+func TestRefundOrder_NeverExceedsPayment(t *testing.T) {
+	r := newTestRepository(t)
+	storeTestOrder(t, r, "a")
+	ctx := context.Background()
+
+	if _, err := r.RefundOrder(ctx, refund("r0", "a", 1818)); !errors.Is(err, ErrRefundExceedsAmount) {
+		t.Errorf("Expected ErrRefundExceedsAmount above the payment, got %v", err)
+	}
+
+	// 300 fits six times into 1817; the row locks let only six through.
+	errs := make(chan error, 10)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.RefundOrder(ctx, refund(fmt.Sprintf("r%d", i+1), "a", 300))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrRefundExceedsAmount):
+			t.Errorf("Expected ErrRefundExceedsAmount, got %v", err)
+		}
+	}
+	if succeeded != 6 {
+		t.Errorf("Expected 6 refunds to fit, got %d", succeeded)
+	}
+	stored, err := r.GetOrderByUID(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	if stored.Payment.Refunded != 1800 {
+		t.Errorf("Expected 1800 refunded, got %d", stored.Payment.Refunded)
+	}
+}
+
+func TestRefundOrder_RejectsRefundedItems(t *testing.T) {
+	r := newTestRepository(t)
+	order := storeTestOrder(t, r, "a")
+	ctx := context.Background()
+	rid := order.Items[0].Rid
+
+	refunded, err := r.RefundOrder(ctx, refund("r1", "a", 0, rid))
+	if err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	if refunded.Payment.Refunded != order.Items[0].TotalPrice || !refunded.Items[0].Refunded || refunded.Items[1].Refunded {
+		t.Errorf("Expected only item %s refunded for its price, got %+v", rid, refunded.Payment)
+	}
+
+	if _, err := r.RefundOrder(ctx, refund("r2", "a", 0, rid)); !errors.Is(err, ErrItemAlreadyRefunded) {
+		t.Errorf("Expected ErrItemAlreadyRefunded, got %v", err)
+	}
+	if _, err := r.RefundOrder(ctx, refund("r3", "a", 0, "unknown")); !errors.Is(err, ErrUnknownItem) {
+		t.Errorf("Expected ErrUnknownItem, got %v", err)
+	}
+	if _, err := r.RefundOrder(ctx, refund("r4", "missing", 100)); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestRefundOrder_CancelRefundsRemainder(t *testing.T) {
+	r := newTestRepository(t)
+	storeTestOrder(t, r, "a")
+	ctx := context.Background()
+
+	if _, err := r.RefundOrder(ctx, refund("r1", "a", 500)); err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	cancel := &model.RefundEvent{RefundID: "c1", OrderUID: "a", Type: model.RefundTypeCancel, Reason: "customer request", OccurredAt: time.Now()}
+	cancelled, err := r.RefundOrder(ctx, cancel)
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	if cancelled.Status != model.StatusCancelled {
+		t.Errorf("Expected status %q, got %q", model.StatusCancelled, cancelled.Status)
+	}
+	last := cancelled.StatusHistory[len(cancelled.StatusHistory)-1]
+	if last.Status != model.StatusCancelled || last.Reason != "customer request" {
+		t.Errorf("Expected the cancellation in the history, got %+v", last)
+	}
+	refunds := cancelled.Payment.Refunds
+	if cancelled.Payment.Refunded != 1817 || len(refunds) != 2 || refunds[1].Amount != 1317 {
+		t.Errorf("Expected the remaining 1317 refunded, got %v", refunds)
+	}
+
+	cancel.RefundID = "c2"
+	var transition *model.TransitionError
+	if _, err := r.RefundOrder(ctx, cancel); !errors.As(err, &transition) {
+		t.Errorf("Expected a TransitionError cancelling twice, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package repotest gives tests a migrated PostgreSQL schema of their own.
+// Tests using it are skipped unless TEST_DATABASE_URL points at a database
+// they may create schemas in.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"order-service/internal/migrate"
+	"order-service/migrations"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const EnvDSN = "TEST_DATABASE_URL"
+
+// DSN creates a schema, applies every migration to it and returns a DSN whose
+// connections work in that schema. The schema is dropped when the test ends,
+// so tests in different packages can share one database.
+func DSN(t testing.TB) string {
+	t.Helper()
+
+	base := os.Getenv(EnvDSN)
+	if base == "" {
+		t.Skipf("%s is not set", EnvDSN)
+	}
+
+	admin, err := sql.Open("postgres", base)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d_%d", os.Getpid(), time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	dsn, err := withSearchPath(base, schema)
+	if err != nil {
+		t.Fatalf("parse %s: %v", EnvDSN, err)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open test schema: %v", err)
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	return dsn
+}
+
+// withSearchPath adds search_path to a URL or key=value DSN; lib/pq passes it
+// on as a run-time parameter of every connection.
+func withSearchPath(dsn, schema string) (string, error) {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrStatusUnchanged is returned when an order already has the requested
+// status, typically because a status event was redelivered.
+var ErrStatusUnchanged = errors.New("order already has this status")
+
+type StatusStore interface {
+	UpdateOrderStatus(ctx context.Context, event *model.StatusEvent) (*model.Order, error)
+}
+
+// UpdateOrderStatus moves an order to event.Status, recording the change in
+// its history, and returns the updated order. Transitions the state machine
+// does not allow fail with *model.TransitionError.
+func (r *PostgresRepository) UpdateOrderStatus(ctx context.Context, event *model.StatusEvent) (*model.Order, error) {
+	defer metrics.ObserveQuery("UpdateOrderStatus")()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current model.OrderStatus
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM orders WHERE order_uid = $1 FOR UPDATE
+	`, event.OrderUID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current == event.Status {
+		return nil, ErrStatusUnchanged
+	}
+	if !current.CanTransition(event.Status) {
+		return nil, &model.TransitionError{From: current, To: event.Status}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = $2 WHERE order_uid = $1
+	`, event.OrderUID, event.Status); err != nil {
+		return nil, err
+	}
+	change := model.StatusChange{Status: event.Status, Reason: event.Reason, ChangedAt: event.OccurredAt}
+	if err := insertStatusChange(ctx, tx, event.OrderUID, &change); err != nil {
+		return nil, err
+	}
+
+	order, err := r.getOrder(ctx, tx, event.OrderUID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// insertStatusChange appends change to the order's history. A zero ChangedAt
+// is filled in with the database time.
+func insertStatusChange(ctx context.Context, tx *sql.Tx, orderUID string, change *model.StatusChange) error {
+	return tx.QueryRowContext(ctx, `
+		INSERT INTO order_status_history (order_uid, status, reason, changed_at)
+		VALUES ($1, $2, $3, COALESCE($4, now()))
+		RETURNING changed_at
+	`, orderUID, change.Status, change.Reason, nullTime(change.ChangedAt)).Scan(&change.ChangedAt)
+}
+
+func loadStatusHistory(ctx context.Context, q querier, uids []string, byUID map[string]*model.Order) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT order_uid, status, reason, changed_at
+		FROM order_status_history WHERE order_uid = ANY($1)
+		ORDER BY order_uid, id
+	`, pq.Array(uids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderUID string
+		var change model.StatusChange
+		if err := rows.Scan(&orderUID, &change.Status, &change.Reason, &change.ChangedAt); err != nil {
+			return err
+		}
+		if order, ok := byUID[orderUID]; ok {
+			order.StatusHistory = append(order.StatusHistory, change)
+		}
+	}
+	return rows.Err()
+}
+
+// nullTime maps the zero time to NULL so the column default applies.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/model"
+	"slices"
+	"testing"
+	"time"
+)
+
+func statuses(history []model.StatusChange) []model.OrderStatus {
+	out := make([]model.OrderStatus, len(history))
+	for i, change := range history {
+		out[i] = change.Status
+	}
+	return out
+}
+
+func TestUpdateOrderStatus_AppendsHistory(t *testing.T) {
+	r := newTestRepository(t)
+	storeTestOrder(t, r, "a")
+	ctx := context.Background()
+	paidAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	order, err := r.UpdateOrderStatus(ctx, &model.StatusEvent{OrderUID: "a", Status: model.StatusPaid, OccurredAt: paidAt})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+	order, err = r.UpdateOrderStatus(ctx, &model.StatusEvent{OrderUID: "a", Status: model.StatusAssembling, Reason: "picked"})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+
+	want := []model.OrderStatus{model.StatusCreated, model.StatusPaid, model.StatusAssembling}
+	if order.Status != model.StatusAssembling || !slices.Equal(statuses(order.StatusHistory), want) {
+		t.Fatalf("Expected %v ending in assembling, got %q with %v", want, order.Status, statuses(order.StatusHistory))
+	}
+	if !order.StatusHistory[1].ChangedAt.Equal(paidAt) {
+		t.Errorf("Expected the event time %v, got %v", paidAt, order.StatusHistory[1].ChangedAt)
+	}
+	if last := order.StatusHistory[2]; last.Reason != "picked" || last.ChangedAt.IsZero() {
+		t.Errorf("Expected the reason and a database time, got %+v", last)
+	}
+
+	stored, err := r.GetOrderByUID(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	if stored.Status != model.StatusAssembling || len(stored.StatusHistory) != 3 {
+		t.Errorf("Expected the stored order to match, got %q with %v", stored.Status, statuses(stored.StatusHistory))
+	}
+}
+
+func TestUpdateOrderStatus_RejectsInvalidTransitions(t *testing.T) {
+	r := newTestRepository(t)
+	storeTestOrder(t, r, "a")
+	ctx := context.Background()
+
+	var transition *model.TransitionError
+	if _, err := r.UpdateOrderStatus(ctx, &model.StatusEvent{OrderUID: "a", Status: model.StatusShipped}); !errors.As(err, &transition) {
+		t.Errorf("Expected a TransitionError skipping to shipped, got %v", err)
+	}
+	if _, err := r.UpdateOrderStatus(ctx, &model.StatusEvent{OrderUID: "a", Status: model.StatusCreated}); !errors.Is(err, ErrStatusUnchanged) {
+		t.Errorf("Expected ErrStatusUnchanged, got %v", err)
+	}
+	if _, err := r.UpdateOrderStatus(ctx, &model.StatusEvent{OrderUID: "missing", Status: model.StatusPaid}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+
+	stored, err := r.GetOrderByUID(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetOrderByUID: %v", err)
+	}
+	if stored.Status != model.StatusCreated || len(stored.StatusHistory) != 1 {
+		t.Errorf("Expected rejected events to leave the order alone, got %q with %v", stored.Status, statuses(stored.StatusHistory))
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"order-service/internal/broker"
+	"order-service/internal/model"
+	"sync"
+	"time"
+)
+
+// BatchOptions bound how many validated orders are collected before they are
+// written together, and how long the first of them waits for the rest.
+type BatchOptions struct {
+	Size     int
+	MaxDelay time.Duration
+}
+
+type pendingOrder struct {
+	msg   broker.Message
+	order *model.Order
+	start time.Time
+}
+
+// orderBatcher collects pending orders and hands them to write once Size of
+// them are waiting or MaxDelay has passed since the first one arrived. A full
+// batch is written on the goroutine that completed it.
+type orderBatcher struct {
+	opts  BatchOptions
+	write func(batch []pendingOrder)
+
+	mu      sync.Mutex
+	pending []pendingOrder
+	timer   *time.Timer
+}
+
+func newOrderBatcher(opts BatchOptions, write func(batch []pendingOrder)) *orderBatcher {
+	return &orderBatcher{opts: opts, write: write}
+}
+
+func (b *orderBatcher) add(p pendingOrder) {
+	b.mu.Lock()
+	b.pending = append(b.pending, p)
+	if len(b.pending) >= b.opts.Size {
+		batch := b.take()
+		b.mu.Unlock()
+		b.write(batch)
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.opts.MaxDelay, b.flush)
+	}
+	b.mu.Unlock()
+}
+
+// flush writes whatever is pending without waiting for the batch to fill.
+func (b *orderBatcher) flush() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	if len(batch) > 0 {
+		b.write(batch)
+	}
+}
+
+func (b *orderBatcher) take() []pendingOrder {
+	batch := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBatchWriter fails each order in failOnce the first time it is written.
+type fakeBatchWriter struct {
+	mu       sync.Mutex
+	batches  [][]string
+	failOnce map[string]bool
+	onWrite  func()
+}
+
+func (f *fakeBatchWriter) CreateOrders(ctx context.Context, orders []*model.Order) []error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.onWrite != nil {
+		f.onWrite()
+	}
+	uids := make([]string, len(orders))
+	errs := make([]error, len(orders))
+	for i, order := range orders {
+		uids[i] = order.OrderUID
+		if f.failOnce[order.OrderUID] {
+			delete(f.failOnce, order.OrderUID)
+			errs[i] = errors.New("value too long for type character varying(255)")
+		}
+	}
+	f.batches = append(f.batches, uids)
+	return errs
+}
+
+func (f *fakeBatchWriter) written() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batches
+}
+
+func TestNatsSubscriber_BatchAcksAfterCommit(t *testing.T) {
+	writer := &fakeBatchWriter{failOnce: map[string]bool{"poison": true}}
+	subscriber, b, orders := newMemorySubscriber(t, &MockRepository{}, func(ns *NatsSubscriber) {
+		ns.SetDeliveryOptions(DeliveryOptions{DurableName: "test", MaxInflight: 3, MaxRedeliveries: 2})
+		ns.SetBatchWriter(writer, BatchOptions{Size: 3, MaxDelay: time.Hour})
+	})
+	var unackedAtWrite []int
+	writer.onWrite = func() { unackedAtWrite = append(unackedAtWrite, b.Unacked("orders")) }
+
+	for _, uid := range []string{"a", "poison", "b", "c"} {
+		b.Publish("orders", orderJSON(uid))
+	}
+	b.Deliver()
+
+	// The poisoned order is retried without holding back the rest of its
+	// batch, and waits with c for the next one.
+	assert.Equal(t, [][]string{{"a", "poison", "b"}}, writer.written())
+	assert.Equal(t, 2, b.Unacked("orders"))
+	assert.Equal(t, 2, orders.Size())
+
+	require.NoError(t, subscriber.Shutdown(context.Background()))
+	assert.Equal(t, [][]string{{"a", "poison", "b"}, {"poison", "c"}}, writer.written())
+	assert.Equal(t, []int{3, 2}, unackedAtWrite, "nothing is acked before its batch is written")
+	assert.Equal(t, 0, b.Unacked("orders"))
+	assert.Equal(t, 4, orders.Size())
+}
+
+func TestNatsSubscriber_BatchFlushesAfterMaxDelay(t *testing.T) {
+	writer := &fakeBatchWriter{}
+	subscriber, b, _ := newMemorySubscriber(t, &MockRepository{}, func(ns *NatsSubscriber) {
+		ns.SetBatchWriter(writer, BatchOptions{Size: 10, MaxDelay: 10 * time.Millisecond})
+	})
+
+	b.Publish("orders", []byte(validOrderJSON))
+	b.Deliver()
+
+	require.Eventually(t, func() bool { return len(writer.written()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return b.Unacked("orders") == 0 }, 5*time.Second, 5*time.Millisecond)
+	require.NoError(t, subscriber.Shutdown(context.Background()))
+}
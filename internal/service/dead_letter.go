@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"order-service/internal/broker"
+	"order-service/internal/model"
+)
+
+// StageError records which step of the ingest pipeline rejected a message.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Stage + ": " + e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Stage returns the pipeline stage that produced err, defaulting to persist.
+func Stage(err error) string {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	return model.StagePersist
+}
+
+func NewDeadLetter(msg broker.Message, err error) *model.DeadLetter {
+	return &model.DeadLetter{
+		Payload:   string(msg.Data()),
+		Sequence:  msg.Sequence(),
+		Timestamp: msg.Timestamp(),
+		Stage:     Stage(err),
+		Error:     err.Error(),
+	}
+}
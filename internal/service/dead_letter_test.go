@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"order-service/internal/broker"
+	"order-service/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDeadLetter(t *testing.T) {
+	b := broker.NewMemory()
+	b.Publish("orders", []byte(`{}`))
+	b.Publish("orders", []byte(`{"order_uid":`))
+	var msg broker.Message
+	require.NoError(t, b.Subscribe("orders", broker.SubscribeOptions{}, func(m broker.Message) { msg = m }))
+	b.Deliver()
+	err := &StageError{Stage: model.StageDecode, Err: errors.New("unexpected end of JSON input")}
+
+	dl := NewDeadLetter(msg, err)
+
+	assert.Equal(t, `{"order_uid":`, dl.Payload)
+	assert.Equal(t, uint64(2), dl.Sequence)
+	assert.True(t, msg.Timestamp().Equal(dl.Timestamp))
+	assert.Equal(t, model.StageDecode, dl.Stage)
+	assert.Contains(t, dl.Error, "unexpected end of JSON input")
+}
+
+func TestStage_DefaultsToPersist(t *testing.T) {
+	assert.Equal(t, model.StageValidate, Stage(&StageError{Stage: model.StageValidate, Err: errors.New("bad")}))
+	assert.Equal(t, model.StagePersist, Stage(errors.New("connection refused")))
+}
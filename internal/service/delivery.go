@@ -0,0 +1,77 @@
+package service
+
+import (
+	"log"
+	"order-service/internal/broker"
+	"order-service/internal/metrics"
+	"time"
+)
+
+type DeliveryOptions struct {
+	DurableName     string
+	AckWait         time.Duration
+	MaxInflight     int
+	MaxRedeliveries int
+	// Backoff sets redelivery delays on JetStream, replacing AckWait.
+	Backoff []time.Duration
+}
+
+func DefaultDeliveryOptions() DeliveryOptions {
+	return DeliveryOptions{
+		DurableName:     "order-service",
+		AckWait:         30 * time.Second,
+		MaxInflight:     16,
+		MaxRedeliveries: 5,
+	}
+}
+
+// SubscribeOptions subscribes in manual ack mode, so a message is only
+// acknowledged once it has been handled. Brokers that cap deliveries stop
+// after the delivery on which RetryOrPark parks the message.
+func (o DeliveryOptions) SubscribeOptions() broker.SubscribeOptions {
+	return broker.SubscribeOptions{
+		Durable:     o.DurableName,
+		AckWait:     o.AckWait,
+		MaxInflight: o.MaxInflight,
+		MaxDeliver:  o.MaxRedeliveries + 1,
+		Backoff:     o.Backoff,
+	}
+}
+
+func Ack(msg broker.Message) {
+	if err := msg.Ack(); err != nil {
+		log.Printf("Failed to ack message %d: %v", msg.Sequence(), err)
+	}
+}
+
+func Nack(msg broker.Message) {
+	if err := msg.Nack(); err != nil {
+		log.Printf("Failed to nack message %d: %v", msg.Sequence(), err)
+	}
+}
+
+// RetryOrPark nacks a failed message so that the broker redelivers it after
+// AckWait. Once MaxRedeliveries is reached the message is parked:
+// handed to park, if set, and acknowledged so it stops blocking the
+// subscription. A park error leaves the message for another redelivery.
+// It reports whether the message was parked.
+func (o DeliveryOptions) RetryOrPark(msg broker.Message, err error, park func(msg broker.Message, err error) error) bool {
+	if msg.Redeliveries() < o.MaxRedeliveries {
+		log.Printf("Message %d failed (redelivery %d/%d), will retry: %v",
+			msg.Sequence(), msg.Redeliveries(), o.MaxRedeliveries, err)
+		Nack(msg)
+		return false
+	}
+
+	metrics.MessagesParked.Inc()
+	log.Printf("Parking message %d after %d redeliveries: %v", msg.Sequence(), msg.Redeliveries(), err)
+	if park != nil {
+		if parkErr := park(msg, err); parkErr != nil {
+			log.Printf("Failed to park message %d: %v", msg.Sequence(), parkErr)
+			Nack(msg)
+			return false
+		}
+	}
+	Ack(msg)
+	return true
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"order-service/internal/metrics"
+	"sync"
+	"time"
+)
+
+// IngestStatus is a snapshot of how far the subscriber has got through the
+// orders channel.
+type IngestStatus struct {
+	Channel             string     `json:"channel"`
+	LastSequence        uint64     `json:"last_sequence"`
+	LastPublishedAt     *time.Time `json:"last_published_at,omitempty"`
+	LastProcessedAt     *time.Time `json:"last_processed_at,omitempty"`
+	LastLatencySeconds  float64    `json:"last_publish_to_persist_seconds"`
+	ChannelLastSequence uint64     `json:"channel_last_sequence"`
+	ChannelCheckedAt    *time.Time `json:"channel_checked_at,omitempty"`
+	LagMessages         uint64     `json:"lag_messages"`
+	MonitorError        string     `json:"monitor_error,omitempty"`
+}
+
+// LagTracker records the position of the subscriber in the channel and
+// compares it with the channel's last sequence reported by NATS Streaming.
+// With MaxInflight above one, messages may finish out of order, so the
+// position is the highest sequence handled and the lag is approximate.
+// JetStream reports the lag itself, see ConsumerPending.
+type LagTracker struct {
+	channel string
+	now     func() time.Time
+
+	mu     sync.Mutex
+	status IngestStatus
+	// pending is set once the broker has reported the lag directly, which
+	// then replaces the one derived from sequences.
+	pending bool
+}
+
+func NewLagTracker(channel string) *LagTracker {
+	return &LagTracker{channel: channel, now: time.Now, status: IngestStatus{Channel: channel}}
+}
+
+// Processed records a message that has been handled for good. persisted is
+// true when the order was committed, which is what publish-to-persist latency
+// measures.
+func (t *LagTracker) Processed(sequence uint64, publishedAt time.Time, persisted bool) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if sequence > t.status.LastSequence {
+		t.status.LastSequence = sequence
+		t.status.LastPublishedAt = &publishedAt
+		metrics.IngestLastSequence.Set(float64(sequence))
+	}
+	t.status.LastProcessedAt = &now
+	if persisted {
+		latency := now.Sub(publishedAt).Seconds()
+		t.status.LastLatencySeconds = latency
+		metrics.PublishToPersist.Observe(latency)
+	}
+	t.updateLag()
+}
+
+// ChannelPosition records the channel's last sequence, or the error reading it.
+func (t *LagTracker) ChannelPosition(lastSequence uint64, err error) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.ChannelCheckedAt = &now
+	if err != nil {
+		t.status.MonitorError = err.Error()
+		return
+	}
+	t.status.MonitorError = ""
+	t.status.ChannelLastSequence = lastSequence
+	metrics.ChannelLastSequence.Set(float64(lastSequence))
+	t.updateLag()
+}
+
+// ConsumerPending records the messages the broker holds for the subscriber's
+// consumer, not yet delivered or awaiting an ack, or the error reading them.
+func (t *LagTracker) ConsumerPending(pending uint64, err error) {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.ChannelCheckedAt = &now
+	if err != nil {
+		t.status.MonitorError = err.Error()
+		return
+	}
+	t.status.MonitorError = ""
+	t.pending = true
+	t.status.LagMessages = pending
+	metrics.ConsumerLag.Set(float64(pending))
+}
+
+func (t *LagTracker) updateLag() {
+	if t.pending {
+		return
+	}
+	t.status.LagMessages = 0
+	if t.status.ChannelLastSequence > t.status.LastSequence {
+		t.status.LagMessages = t.status.ChannelLastSequence - t.status.LastSequence
+	}
+	metrics.ConsumerLag.Set(float64(t.status.LagMessages))
+}
+
+func (t *LagTracker) Status() IngestStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// LagSource reads the broker's side of the lag and records it in a tracker.
+type LagSource interface {
+	UpdateLag(ctx context.Context, t *LagTracker) error
+}
+
+// Poll asks source for the channel position every interval until ctx is done.
+func (t *LagTracker) Poll(ctx context.Context, source LagSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := source.UpdateLag(ctx, t); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to read channel %s position: %v", t.channel, err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ChannelMonitor reads channel state from the NATS Streaming monitoring
+// endpoint, e.g. http://localhost:8222.
+type ChannelMonitor struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewChannelMonitor(baseURL string) *ChannelMonitor {
+	return &ChannelMonitor{baseURL: baseURL, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (m *ChannelMonitor) UpdateLag(ctx context.Context, t *LagTracker) error {
+	seq, err := m.LastSequence(ctx, t.channel)
+	t.ChannelPosition(seq, err)
+	return err
+}
+
+func (m *ChannelMonitor) LastSequence(ctx context.Context, channel string) (uint64, error) {
+	endpoint := m.baseURL + "/streaming/channelsz?channel=" + url.QueryEscape(channel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	var info struct {
+		LastSeq uint64 `json:"last_seq"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return 0, fmt.Errorf("decode channelsz: %w", err)
+	}
+	return info.LastSeq, nil
+}
+
+// PendingCounter reports how many messages of a channel a durable consumer
+// has yet to get acked. broker.JetStream implements it.
+type PendingCounter interface {
+	Pending(ctx context.Context, durable, channel string) (uint64, error)
+}
+
+// ConsumerMonitor reads the lag from the JetStream consumer of the channel,
+// which has no channel sequence of its own to compare with.
+type ConsumerMonitor struct {
+	counter PendingCounter
+	durable string
+}
+
+func NewConsumerMonitor(counter PendingCounter, durable string) *ConsumerMonitor {
+	return &ConsumerMonitor{counter: counter, durable: durable}
+}
+
+func (m *ConsumerMonitor) UpdateLag(ctx context.Context, t *LagTracker) error {
+	pending, err := m.counter.Pending(ctx, m.durable, t.channel)
+	t.ConsumerPending(pending, err)
+	return err
+}
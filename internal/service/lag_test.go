@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLagTracker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewLagTracker("orders")
+	tracker.now = func() time.Time { return now }
+
+	tracker.ChannelPosition(10, nil)
+	assert.Equal(t, uint64(10), tracker.Status().LagMessages)
+
+	tracker.Processed(4, now.Add(-2*time.Second), true)
+	tracker.Processed(3, now.Add(-time.Second), false)
+
+	status := tracker.Status()
+	assert.Equal(t, uint64(4), status.LastSequence)
+	assert.Equal(t, uint64(6), status.LagMessages)
+	assert.Equal(t, 2.0, status.LastLatencySeconds)
+
+	tracker.ChannelPosition(0, assert.AnError)
+	status = tracker.Status()
+	assert.Equal(t, assert.AnError.Error(), status.MonitorError)
+	assert.Equal(t, uint64(6), status.LagMessages)
+
+	tracker.Processed(12, now, true)
+	assert.Zero(t, tracker.Status().LagMessages)
+}
+
+func TestChannelMonitor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/streaming/channelsz" || r.URL.Query().Get("channel") != "orders" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"name":"orders","msgs":3,"last_seq":42}`))
+	}))
+	defer server.Close()
+
+	monitor := NewChannelMonitor(server.URL)
+	seq, err := monitor.LastSequence(context.Background(), "orders")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), seq)
+
+	_, err = monitor.LastSequence(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+type fakePendingCounter struct {
+	pending uint64
+	err     error
+}
+
+func (f *fakePendingCounter) Pending(ctx context.Context, durable, channel string) (uint64, error) {
+	if durable != "order-service" || channel != "orders" {
+		return 0, assert.AnError
+	}
+	return f.pending, f.err
+}
+
+func TestConsumerMonitor(t *testing.T) {
+	tracker := NewLagTracker("orders")
+	counter := &fakePendingCounter{pending: 7}
+	monitor := NewConsumerMonitor(counter, "order-service")
+
+	require.NoError(t, monitor.UpdateLag(context.Background(), tracker))
+	assert.Equal(t, uint64(7), tracker.Status().LagMessages)
+
+	// Sequences span every subject of the stream, so they leave the lag alone.
+	tracker.Processed(100, time.Now(), true)
+	assert.Equal(t, uint64(7), tracker.Status().LagMessages)
+
+	counter.err = assert.AnError
+	assert.Error(t, monitor.UpdateLag(context.Background(), tracker))
+	status := tracker.Status()
+	assert.Equal(t, assert.AnError.Error(), status.MonitorError)
+	assert.Equal(t, uint64(7), status.LagMessages)
+}
@@ -3,62 +3,381 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"order-service/internal/broker"
+	"order-service/internal/metrics"
 	"order-service/internal/model"
 	"order-service/internal/repository"
-
-	"github.com/nats-io/stan.go"
+	"sync"
+	"time"
 )
 
-type NatsSubscriber struct {
-	repo    repository.OrderRepository
-	cache   *Cache
-	cluster string
-	client  string
+// OrderCache is the part of cache.Cache the subscriber writes to.
+type OrderCache interface {
+	Set(order *model.Order)
 }
 
-type Cache struct {
-	Orders map[string]interface{}
+type DeadLetterSaver interface {
+	SaveDeadLetter(ctx context.Context, dl *model.DeadLetter) error
 }
 
-func NewNatsSubscriber(repo repository.OrderRepository, cache *Cache, cluster, client string) *NatsSubscriber {
+type NatsSubscriber struct {
+	repo        repository.OrderRepository
+	cache       OrderCache
+	deadLetters DeadLetterSaver
+	statuses    repository.StatusStore
+	refunds     repository.RefundStore
+	lag         *LagTracker
+	consumer    broker.Consumer
+	delivery    DeliveryOptions
+	workers     *WorkerPool
+	batchWriter repository.BatchWriter
+	batch       *orderBatcher
+	// channels maps each dead letter kind to the channel it is consumed
+	// from. It is filled before the subscription starts.
+	channels map[string]string
+
+	mu       sync.Mutex
+	closing  bool
+	inflight sync.WaitGroup
+}
+
+// NewNatsSubscriber creates a subscriber consuming through consumer, which it
+// closes on Shutdown.
+func NewNatsSubscriber(repo repository.OrderRepository, cache OrderCache, consumer broker.Consumer) *NatsSubscriber {
 	return &NatsSubscriber{
-		repo:    repo,
-		cache:   cache,
-		cluster: cluster,
-		client:  client,
+		repo:     repo,
+		cache:    cache,
+		consumer: consumer,
+		delivery: DefaultDeliveryOptions(),
+		channels: make(map[string]string),
+	}
+}
+
+func (ns *NatsSubscriber) SetDeliveryOptions(opts DeliveryOptions) {
+	ns.delivery = opts
+}
+
+// SetDeadLetterStore enables persisting rejected and parked messages. Without
+// it they are only logged.
+func (ns *NatsSubscriber) SetDeadLetterStore(store DeadLetterSaver) {
+	ns.deadLetters = store
+}
+
+func (ns *NatsSubscriber) SetStatusStore(store repository.StatusStore) {
+	ns.statuses = store
+}
+
+// SetLagTracker records the position of every handled order message in t.
+func (ns *NatsSubscriber) SetLagTracker(t *LagTracker) {
+	ns.lag = t
+}
+
+func (ns *NatsSubscriber) SetRefundStore(store repository.RefundStore) {
+	ns.refunds = store
+}
+
+// SetWorkerPool hands messages of every channel to pool, partitioned by
+// order_uid. Without a pool they are handled on the broker's delivery
+// goroutine. Shutdown stops the pool.
+func (ns *NatsSubscriber) SetWorkerPool(pool *WorkerPool) {
+	ns.workers = pool
+}
+
+// SetBatchWriter writes orders through w in batches bounded by opts instead
+// of one CreateOrder per message. Messages are acknowledged only after their
+// batch commits. A Size below 2 disables batching.
+func (ns *NatsSubscriber) SetBatchWriter(w repository.BatchWriter, opts BatchOptions) {
+	if opts.Size < 2 {
+		ns.batchWriter, ns.batch = nil, nil
+		return
 	}
+	ns.batchWriter = w
+	ns.batch = newOrderBatcher(opts, ns.writeBatch)
 }
 
+// Subscribe starts consuming orders from channel.
 func (ns *NatsSubscriber) Subscribe(channel string) error {
-	sc, err := stan.Connect(ns.cluster, ns.client)
-	if err != nil {
-		return err
+	return ns.subscribe(model.DeadLetterOrder, channel, ns.handleMessage)
+}
+
+// SubscribeStatus starts consuming order status events from channel. It needs
+// a status store.
+func (ns *NatsSubscriber) SubscribeStatus(channel string) error {
+	if ns.statuses == nil {
+		return errors.New("no status store configured")
 	}
+	return ns.subscribe(model.DeadLetterStatus, channel, ns.handleStatus)
+}
 
-	_, err = sc.Subscribe(channel, func(msg *stan.Msg) {
-		var order model.Order
-		
-		if err := json.Unmarshal(msg.Data, &order); err != nil {
-			log.Printf("Invalid JSON received: %v", err)
-			return
-		}
+// SubscribeRefunds starts consuming refund and cancellation events from
+// channel. It needs a refund store.
+func (ns *NatsSubscriber) SubscribeRefunds(channel string) error {
+	if ns.refunds == nil {
+		return errors.New("no refund store configured")
+	}
+	return ns.subscribe(model.DeadLetterRefund, channel, ns.handleRefund)
+}
 
-		if err := order.Validate(); err != nil {
-			log.Printf("Invalid order data: %v", err)
-			return
-		}
+func (ns *NatsSubscriber) subscribe(kind, channel string, h broker.Handler) error {
+	if ns.consumer == nil {
+		return errors.New("not connected")
+	}
+	ns.channels[kind] = channel
+	return ns.consumer.Subscribe(channel, ns.delivery.SubscribeOptions(), ns.dispatch(h))
+}
 
-		ctx := context.Background()
-		if err := ns.repo.CreateOrder(ctx, &order); err != nil {
-			log.Printf("Failed to save order to DB: %v", err)
-			return
+// dispatch runs h on the worker pool, if any. A message submitted after the
+// pool stopped stays unacknowledged and is redelivered later.
+func (ns *NatsSubscriber) dispatch(h broker.Handler) broker.Handler {
+	if ns.workers == nil {
+		return h
+	}
+	return func(msg broker.Message) {
+		ns.workers.Submit(partitionKey(msg), func() { h(msg) })
+	}
+}
+
+// Healthy reports whether the subscriber is connected to the broker.
+func (ns *NatsSubscriber) Healthy(ctx context.Context) error {
+	ns.mu.Lock()
+	closing := ns.closing
+	ns.mu.Unlock()
+
+	switch {
+	case closing:
+		return errors.New("shutting down")
+	case ns.consumer == nil:
+		return errors.New("not connected")
+	}
+	return ns.consumer.Healthy()
+}
+
+// Shutdown stops handling new messages, waits for in-flight ones to finish
+// their transactions, then closes the consumer. Durable subscriptions survive
+// a restart. Messages that arrive while shutting down stay unacknowledged and
+// are redelivered later.
+func (ns *NatsSubscriber) Shutdown(ctx context.Context) error {
+	ns.mu.Lock()
+	ns.closing = true
+	ns.mu.Unlock()
+
+	if ns.batch != nil {
+		ns.batch.flush()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ns.inflight.Wait()
+		close(done)
+	}()
+
+	var errs []error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("waiting for in-flight messages: %w", ctx.Err()))
+	}
+
+	if ns.consumer != nil {
+		if err := ns.consumer.Close(); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	if ns.workers != nil {
+		ns.workers.Stop()
+	}
+	return errors.Join(errs...)
+}
+
+// Ingest runs a raw message through decode, validate and persist and caches
+// the stored order. Failures are returned as *StageError.
+func (ns *NatsSubscriber) Ingest(ctx context.Context, data []byte) (*model.Order, error) {
+	order, err := decodeOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := ns.stored(order, ns.repo.CreateOrder(ctx, order)); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func decodeOrder(data []byte) (*model.Order, error) {
+	var order model.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		metrics.MessagesRejected.WithLabelValues(model.StageDecode).Inc()
+		return nil, &StageError{Stage: model.StageDecode, Err: err}
+	}
+
+	if err := order.Validate(); err != nil {
+		metrics.MessagesRejected.WithLabelValues(model.StageValidate).Inc()
+		return nil, &StageError{Stage: model.StageValidate, Err: err}
+	}
+	metrics.MessagesValidated.Inc()
+	return &order, nil
+}
+
+// stored records the outcome err of persisting order and caches the order
+// if it is stored. Failures are returned as *StageError.
+func (ns *NatsSubscriber) stored(order *model.Order, err error) error {
+	switch {
+	case err == nil:
+		metrics.MessagesPersisted.WithLabelValues("created").Inc()
+	case errors.Is(err, repository.ErrDuplicateOrder):
+		metrics.MessagesPersisted.WithLabelValues("duplicate").Inc()
+		log.Printf("Order %s already stored, skipping", order.OrderUID)
+	case errors.Is(err, repository.ErrOrderConflict):
+		metrics.MessagesPersisted.WithLabelValues("conflict").Inc()
+		return &StageError{Stage: model.StagePersist, Err: err}
+	default:
+		metrics.MessagesPersisted.WithLabelValues("error").Inc()
+		return &StageError{Stage: model.StagePersist, Err: err}
+	}
+
+	ns.cache.Set(order)
+	return nil
+}
+
+func (ns *NatsSubscriber) handleMessage(msg broker.Message) {
+	ns.mu.Lock()
+	if ns.closing {
+		ns.mu.Unlock()
+		return
+	}
+	ns.inflight.Add(1)
+	ns.mu.Unlock()
+	defer ns.inflight.Done()
+
+	metrics.MessagesReceived.Inc()
+	start := time.Now()
+
+	if ns.batch == nil {
+		order, err := ns.Ingest(context.Background(), msg.Data())
+		ns.finish(msg, order, err, start)
+		return
+	}
+
+	order, err := decodeOrder(msg.Data())
+	if err != nil {
+		ns.finish(msg, nil, err, start)
+		return
+	}
+	// The message stays in flight until its batch is written.
+	ns.inflight.Add(1)
+	ns.batch.add(pendingOrder{msg: msg, order: order, start: start})
+}
+
+// writeBatch stores a batch with one CreateOrders call, then acks, retries
+// or parks each message according to its own order's outcome.
+func (ns *NatsSubscriber) writeBatch(batch []pendingOrder) {
+	metrics.BatchSize.Observe(float64(len(batch)))
+
+	orders := make([]*model.Order, len(batch))
+	for i, p := range batch {
+		orders[i] = p.order
+	}
+	errs := ns.batchWriter.CreateOrders(context.Background(), orders)
+	for i, p := range batch {
+		ns.finish(p.msg, p.order, ns.stored(p.order, errs[i]), p.start)
+		ns.inflight.Done()
+	}
+}
 
-		ns.cache.Orders[order.OrderUID] = &order
-		
+// finish acknowledges, dead-letters or retries msg once its order has been
+// ingested with err.
+func (ns *NatsSubscriber) finish(msg broker.Message, order *model.Order, err error, start time.Time) {
+	defer func() {
+		metrics.IngestDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	switch {
+	case err == nil:
+		Ack(msg)
+		ns.processed(msg, true)
 		log.Printf("Order %s processed successfully", order.OrderUID)
-	}, stan.DurableName("order-service"))
+	case errors.Is(err, repository.ErrOrderConflict):
+		log.Printf("Message %d rejected: %v", msg.Sequence(), err)
+		Ack(msg)
+		ns.processed(msg, false)
+	case Stage(err) != model.StagePersist:
+		log.Printf("Message %d rejected: %v", msg.Sequence(), err)
+		if ns.reject(msg, err, model.DeadLetterOrder) {
+			ns.processed(msg, false)
+		}
+	default:
+		log.Printf("Failed to save order to DB: %v", err)
+		if ns.delivery.RetryOrPark(msg, err, ns.parkAs(model.DeadLetterOrder)) {
+			ns.processed(msg, false)
+		}
+	}
+}
 
-	return err
-}
\ No newline at end of file
+// reject stores a message that can never be handled as a dead letter of
+// the given kind and acknowledges it. If the dead letter cannot be stored
+// the message is nacked instead, so that it is not lost. It reports whether
+// the message was acknowledged.
+func (ns *NatsSubscriber) reject(msg broker.Message, err error, kind string) bool {
+	if err := ns.parkAs(kind)(msg, err); err != nil {
+		log.Printf("Failed to store dead letter for message %d: %v", msg.Sequence(), err)
+		Nack(msg)
+		return false
+	}
+	Ack(msg)
+	return true
+}
+
+func (ns *NatsSubscriber) processed(msg broker.Message, persisted bool) {
+	if ns.lag != nil {
+		ns.lag.Processed(msg.Sequence(), msg.Timestamp(), persisted)
+	}
+}
+
+// parkAs returns a park func for RetryOrPark that stores messages as dead
+// letters of the given kind.
+func (ns *NatsSubscriber) parkAs(kind string) func(msg broker.Message, err error) error {
+	return func(msg broker.Message, err error) error {
+		if ns.deadLetters == nil {
+			return nil
+		}
+		dl := NewDeadLetter(msg, err)
+		dl.Kind, dl.Channel = kind, ns.channels[kind]
+		return ns.deadLetters.SaveDeadLetter(context.Background(), dl)
+	}
+}
+
+// Replay runs the payload of a dead letter of the given kind through the
+// pipeline for that kind again. Status and refund events that turn out to be
+// applied already count as replayed.
+func (ns *NatsSubscriber) Replay(ctx context.Context, kind string, data []byte) error {
+	switch kind {
+	case model.DeadLetterOrder, "":
+		_, err := ns.Ingest(ctx, data)
+		return err
+	case model.DeadLetterStatus:
+		if ns.statuses == nil {
+			return errors.New("no status store configured")
+		}
+		_, err := ns.ApplyStatus(ctx, data)
+		if errors.Is(err, repository.ErrStatusUnchanged) {
+			return nil
+		}
+		return err
+	case model.DeadLetterRefund:
+		if ns.refunds == nil {
+			return errors.New("no refund store configured")
+		}
+		event, err := decodeRefund(data)
+		if err != nil {
+			return err
+		}
+		_, err = ns.ApplyRefund(ctx, event)
+		if errors.Is(err, repository.ErrDuplicateRefund) {
+			return nil
+		}
+		return err
+	}
+	return fmt.Errorf("unknown dead letter kind %q", kind)
+}
@@ -1,37 +1,302 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"order-service/internal/broker"
+	"order-service/internal/broker/brokertest"
+	"order-service/internal/cache"
 	"order-service/internal/model"
+	"order-service/internal/repository"
+	"order-service/internal/repository/repotest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+const validOrderJSON = `{
+	"order_uid": "test123",
+	"track_number": "TRACK123",
+	"delivery": {"name": "Test User"},
+	"payment": {"transaction": "test123", "currency": "USD", "amount": 100, "goods_total": 100},
+	"items": [{"name": "Test Item", "track_number": "TRACK123", "total_price": 100}]
+}`
+
 func TestNatsSubscriber_Creation(t *testing.T) {
 	mockRepo := &MockRepository{}
-	mockCache := &Cache{Orders: make(map[string]interface{})}
-	
-	subscriber := NewNatsSubscriber(mockRepo, mockCache, "test-cluster", "test-client")
-	
+
+	subscriber := NewNatsSubscriber(mockRepo, cache.New(), nil)
+
 	if subscriber == nil {
 		t.Error("NewNatsSubscriber should return non-nil value")
 	}
-	
-	if subscriber.cluster != "test-cluster" {
-		t.Errorf("Expected cluster 'test-cluster', got '%s'", subscriber.cluster)
+
+	if subscriber.delivery.DurableName != "order-service" {
+		t.Errorf("Expected default durable name 'order-service', got '%s'", subscriber.delivery.DurableName)
+	}
+}
+
+func TestNatsSubscriber_IngestStoresAndCaches(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(nil)
+	orders := cache.New()
+	subscriber := NewNatsSubscriber(mockRepo, orders, nil)
+
+	order, err := subscriber.Ingest(context.Background(), []byte(validOrderJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "test123", order.OrderUID)
+
+	cached, exists := orders.Get("test123")
+	assert.True(t, exists)
+	assert.Equal(t, order, cached)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNatsSubscriber_IngestDuplicateIsNotAnError(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(repository.ErrDuplicateOrder)
+	orders := cache.New()
+	subscriber := NewNatsSubscriber(mockRepo, orders, nil)
+
+	_, err := subscriber.Ingest(context.Background(), []byte(validOrderJSON))
+	require.NoError(t, err)
+	assert.Equal(t, 1, orders.Size())
+}
+
+func TestNatsSubscriber_IngestStages(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		repoErr error
+		stage   string
+	}{
+		{"invalid json", `{"order_uid":`, nil, model.StageDecode},
+		{"invalid order", `{"order_uid": "test123"}`, nil, model.StageValidate},
+		{"database failure", validOrderJSON, errors.New("connection refused"), model.StagePersist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(tt.repoErr)
+			orders := cache.New()
+			subscriber := NewNatsSubscriber(mockRepo, orders, nil)
+
+			_, err := subscriber.Ingest(context.Background(), []byte(tt.data))
+			require.Error(t, err)
+			assert.Equal(t, tt.stage, Stage(err))
+			assert.Equal(t, 0, orders.Size())
+		})
+	}
+}
+
+func TestNatsSubscriber_ShutdownWaitsForInflight(t *testing.T) {
+	subscriber := NewNatsSubscriber(&MockRepository{}, cache.New(), nil)
+	subscriber.inflight.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Error(t, subscriber.Shutdown(ctx), "Shutdown should time out while a message is in flight")
+
+	subscriber.inflight.Done()
+	assert.NoError(t, subscriber.Shutdown(context.Background()))
+}
+
+func TestNatsSubscriber_IgnoresMessagesAfterShutdown(t *testing.T) {
+	mockRepo := &MockRepository{}
+	subscriber, b, _ := newMemorySubscriber(t, mockRepo)
+	b.Publish("orders", []byte(validOrderJSON))
+
+	subscriber.mu.Lock()
+	subscriber.closing = true
+	subscriber.mu.Unlock()
+	b.Deliver()
+
+	mockRepo.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+	assert.Equal(t, 1, b.Unacked("orders"), "the message should be left for redelivery")
+}
+
+func TestNatsSubscriber_ConsumesFromBrokers(t *testing.T) {
+	for _, kind := range broker.Kinds {
+		t.Run(kind, func(t *testing.T) {
+			b, err := broker.Dial(brokertest.Run(t, kind))
+			require.NoError(t, err)
+
+			mockRepo := &MockRepository{}
+			mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+			mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(nil).Once()
+			orders := cache.New()
+			subscriber := NewNatsSubscriber(mockRepo, orders, b)
+			opts := DefaultDeliveryOptions()
+			opts.AckWait = time.Second
+			subscriber.SetDeliveryOptions(opts)
+			require.NoError(t, subscriber.Subscribe("orders"))
+			require.NoError(t, subscriber.Healthy(context.Background()))
+
+			require.NoError(t, b.PublishAsync("orders", "", []byte(validOrderJSON), func(string, error) {}))
+
+			// The failed write is retried once the message is redelivered.
+			assert.Eventually(t, func() bool {
+				_, exists := orders.Get("test123")
+				return exists
+			}, 10*time.Second, 50*time.Millisecond)
+			require.NoError(t, subscriber.Shutdown(context.Background()))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// newMemorySubscriber subscribes to "orders" on an in-memory broker, one
+// message at a time and parking after two redeliveries, unless setup changes
+// that before subscribing.
+func newMemorySubscriber(t *testing.T, repo repository.OrderRepository, setup ...func(*NatsSubscriber)) (*NatsSubscriber, *broker.Memory, *cache.Cache) {
+	t.Helper()
+	b := broker.NewMemory()
+	orders := cache.New()
+	subscriber := NewNatsSubscriber(repo, orders, b)
+	subscriber.SetDeliveryOptions(DeliveryOptions{DurableName: "test", AckWait: time.Second, MaxInflight: 1, MaxRedeliveries: 2})
+	for _, f := range setup {
+		f(subscriber)
 	}
+	require.NoError(t, subscriber.Subscribe("orders"))
+	return subscriber, b, orders
 }
 
-func TestCache_Storage(t *testing.T) {
-	cache := &Cache{Orders: make(map[string]interface{})}
-	order := &model.Order{OrderUID: "test123"}
-	
-	cache.Orders[order.OrderUID] = order
-	
-	stored, exists := cache.Orders["test123"]
-	if !exists {
-		t.Error("Order should exist in cache")
+// fakeDeadLetters fails the first failures saves.
+type fakeDeadLetters struct {
+	saved    []*model.DeadLetter
+	failures int
+}
+
+func (f *fakeDeadLetters) SaveDeadLetter(ctx context.Context, dl *model.DeadLetter) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("connection refused")
 	}
-	
-	if stored.(*model.Order).OrderUID != "test123" {
-		t.Error("Stored order should have correct OrderUID")
+	f.saved = append(f.saved, dl)
+	return nil
+}
+
+func orderJSON(uid string) []byte {
+	return []byte(strings.ReplaceAll(validOrderJSON, "test123", uid))
+}
+
+func TestNatsSubscriber_PipelineStoresAndAcks(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(nil).Once()
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(repository.ErrDuplicateOrder).Once()
+	subscriber, b, orders := newMemorySubscriber(t, mockRepo)
+	lag := NewLagTracker("orders")
+	subscriber.SetLagTracker(lag)
+
+	b.Publish("orders", []byte(validOrderJSON))
+	b.Publish("orders", []byte(validOrderJSON))
+	assert.Equal(t, 2, b.Deliver())
+
+	_, exists := orders.Get("test123")
+	assert.True(t, exists)
+	assert.Equal(t, 0, b.Unacked("orders"), "a redelivered duplicate is acked too")
+	assert.Equal(t, uint64(2), lag.Status().LastSequence)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNatsSubscriber_RetriesInOrder(t *testing.T) {
+	var stored []string
+	mockRepo := &MockRepository{}
+	record := func(args mock.Arguments) {
+		stored = append(stored, args.Get(1).(*model.Order).OrderUID)
 	}
-}
\ No newline at end of file
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Run(record).Once()
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(nil).Run(record)
+	subscriber, b, orders := newMemorySubscriber(t, mockRepo)
+
+	b.Publish("orders", orderJSON("first"))
+	b.Publish("orders", orderJSON("second"))
+	b.Deliver()
+
+	// With one message in flight the failed order is retried before the next.
+	assert.Equal(t, []string{"first", "first", "second"}, stored)
+	assert.Equal(t, 2, orders.Size())
+	assert.Equal(t, 0, b.Unacked("orders"))
+	require.NoError(t, subscriber.Shutdown(context.Background()))
+}
+
+func TestNatsSubscriber_ParksAfterMaxRedeliveries(t *testing.T) {
+	mockRepo := &MockRepository{}
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+	subscriber, b, orders := newMemorySubscriber(t, mockRepo)
+	deadLetters := &fakeDeadLetters{}
+	subscriber.SetDeadLetterStore(deadLetters)
+
+	b.Publish("orders", []byte(validOrderJSON))
+	assert.Equal(t, 3, b.Deliver(), "one delivery and two redeliveries")
+
+	require.Len(t, deadLetters.saved, 1)
+	assert.Equal(t, model.StagePersist, deadLetters.saved[0].Stage)
+	assert.Equal(t, uint64(1), deadLetters.saved[0].Sequence)
+	assert.Equal(t, 0, b.Unacked("orders"))
+	assert.Equal(t, 0, orders.Size())
+}
+
+func TestNatsSubscriber_RejectsWithoutRetry(t *testing.T) {
+	mockRepo := &MockRepository{}
+	subscriber, b, _ := newMemorySubscriber(t, mockRepo)
+	deadLetters := &fakeDeadLetters{}
+	subscriber.SetDeadLetterStore(deadLetters)
+
+	b.Publish("orders", []byte(`{"order_uid":`))
+	b.Publish("orders", []byte(`{"order_uid": "test123"}`))
+	assert.Equal(t, 2, b.Deliver())
+
+	require.Len(t, deadLetters.saved, 2)
+	assert.Equal(t, model.StageDecode, deadLetters.saved[0].Stage)
+	assert.Equal(t, model.StageValidate, deadLetters.saved[1].Stage)
+	assert.Equal(t, 0, b.Unacked("orders"))
+	mockRepo.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
+
+func TestNatsSubscriber_RedeliversWhenDeadLetterFails(t *testing.T) {
+	subscriber, b, _ := newMemorySubscriber(t, &MockRepository{})
+	deadLetters := &fakeDeadLetters{failures: 2}
+	subscriber.SetDeadLetterStore(deadLetters)
+
+	b.Publish("orders", []byte(`{"order_uid":`))
+	assert.Equal(t, 3, b.Deliver())
+
+	assert.Len(t, deadLetters.saved, 1)
+	assert.Equal(t, 0, b.Unacked("orders"))
+}
+
+func TestNatsSubscriber_RetriesUnrecordedConflict(t *testing.T) {
+	dsn := repotest.DSN(t)
+	repo, err := repository.NewPostgresRepository(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	deadLetters := &fakeDeadLetters{}
+	_, b, _ := newMemorySubscriber(t, repo, func(ns *NatsSubscriber) {
+		ns.SetDeadLetterStore(deadLetters)
+	})
+
+	b.Publish("orders", []byte(validOrderJSON))
+	b.Deliver()
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("DROP TABLE order_conflicts")
+	require.NoError(t, err)
+
+	b.Publish("orders", []byte(strings.Replace(validOrderJSON, "TRACK123", "CHANGED", 1)))
+	b.Deliver()
+
+	// With nowhere to record the conflict the payload is retried and parked,
+	// not acked and lost.
+	require.Len(t, deadLetters.saved, 1)
+	assert.Equal(t, model.StagePersist, deadLetters.saved[0].Stage)
+	assert.Contains(t, deadLetters.saved[0].Error, "record conflicting order")
+	assert.Equal(t, 0, b.Unacked("orders"))
+}
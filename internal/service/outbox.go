@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"order-service/internal/broker"
+	"order-service/internal/metrics"
+	"order-service/internal/repository"
+	"time"
+)
+
+// outboxAckTimeout bounds the wait for the broker to confirm a relayed batch.
+const outboxAckTimeout = 30 * time.Second
+
+// OutboxRelay publishes events from the outbox to one subject and deletes
+// them once the broker has stored them. An event that fails to publish, or
+// whose deletion is lost, is published again on a later run, so delivery is
+// at least once. The event ID is sent as the message ID, letting JetStream
+// drop such repeats; consumers on NATS Streaming should dedup by event_id.
+type OutboxRelay struct {
+	store     repository.OutboxStore
+	publisher broker.Publisher
+	subject   string
+	batchSize int
+}
+
+func NewOutboxRelay(store repository.OutboxStore, publisher broker.Publisher, subject string) *OutboxRelay {
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		subject:   subject,
+		batchSize: 100,
+	}
+}
+
+// SetBatchSize sets how many events one run reads from the outbox.
+func (r *OutboxRelay) SetBatchSize(n int) {
+	r.batchSize = n
+}
+
+// Run relays pending events every interval until ctx is done. A full batch
+// is followed by the next one right away.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Outbox relay failed: %v", err)
+		}
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type relayResult struct {
+	id  int64
+	err error
+}
+
+// RelayOnce publishes one batch of pending events, oldest first, and deletes
+// those the broker acknowledged. It returns how many events were read.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.store.PendingEvents(ctx, r.batchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	var errs []error
+	results := make(chan relayResult, len(events))
+	sent := 0
+	for _, event := range events {
+		err := r.publisher.PublishAsync(r.subject, event.EventID, event.Payload, func(_ string, err error) {
+			results <- relayResult{id: event.ID, err: err}
+		})
+		if err != nil {
+			// Later events wait, so that they do not overtake this one.
+			metrics.OutboxEvents.WithLabelValues("failed").Inc()
+			errs = append(errs, fmt.Errorf("publish event %s: %w", event.EventID, err))
+			break
+		}
+		sent++
+	}
+
+	var published []int64
+	timeout := time.NewTimer(outboxAckTimeout)
+	defer timeout.Stop()
+wait:
+	for range sent {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				metrics.OutboxEvents.WithLabelValues("failed").Inc()
+				errs = append(errs, fmt.Errorf("event %d not acknowledged: %w", res.id, res.err))
+				continue
+			}
+			metrics.OutboxEvents.WithLabelValues("published").Inc()
+			published = append(published, res.id)
+		case <-timeout.C:
+			errs = append(errs, errors.New("timed out waiting for publish acks"))
+			break wait
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+			break wait
+		}
+	}
+
+	if len(published) > 0 {
+		// Delete even while shutting down, or every published event would go
+		// out again after a restart.
+		if err := r.store.DeleteEvents(context.WithoutCancel(ctx), published); err != nil {
+			errs = append(errs, fmt.Errorf("delete published events: %w", err))
+		}
+	}
+	return len(events), errors.Join(errs...)
+}
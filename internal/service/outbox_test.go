@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"order-service/internal/broker"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"order-service/internal/repository/repotest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox fails the first deleteFailures deletes.
+type fakeOutbox struct {
+	events         []*model.OutboxEvent
+	deleteFailures int
+}
+
+func (f *fakeOutbox) add(t *testing.T, uid string) {
+	t.Helper()
+	order := &model.Order{OrderUID: uid, Status: model.StatusCreated}
+	event, err := model.NewOrderAcceptedEvent(order)
+	require.NoError(t, err)
+	event.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, event)
+}
+
+func (f *fakeOutbox) PendingEvents(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	return f.events[:min(limit, len(f.events))], nil
+}
+
+func (f *fakeOutbox) DeleteEvents(ctx context.Context, ids []int64) error {
+	if f.deleteFailures > 0 {
+		f.deleteFailures--
+		return errors.New("connection refused")
+	}
+	f.events = slices.DeleteFunc(f.events, func(e *model.OutboxEvent) bool {
+		return slices.Contains(ids, e.ID)
+	})
+	return nil
+}
+
+// acceptedOrders subscribes to the subject and returns the order UIDs of the
+// events delivered so far.
+func acceptedOrders(t *testing.T, b *broker.Memory, subject string) func() []string {
+	t.Helper()
+	var uids []string
+	err := b.Subscribe(subject, broker.SubscribeOptions{Durable: "downstream"}, func(msg broker.Message) {
+		var event model.OrderAccepted
+		require.NoError(t, json.Unmarshal(msg.Data(), &event))
+		assert.Equal(t, model.EventOrderAccepted+":"+event.Order.OrderUID, event.EventID)
+		uids = append(uids, event.Order.OrderUID)
+		msg.Ack()
+	})
+	require.NoError(t, err)
+	return func() []string {
+		b.Deliver()
+		return uids
+	}
+}
+
+func TestOutboxRelay_PublishesInOrder(t *testing.T) {
+	store := &fakeOutbox{}
+	for _, uid := range []string{"a", "b", "c"} {
+		store.add(t, uid)
+	}
+	b := broker.NewMemory()
+	received := acceptedOrders(t, b, "order.accepted")
+	relay := NewOutboxRelay(store, b, "order.accepted")
+	relay.SetBatchSize(2)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"a", "b"}, received())
+	require.Len(t, store.events, 1)
+
+	_, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, received())
+	assert.Empty(t, store.events)
+}
+
+func TestOutboxRelay_RepublishesWithSameID(t *testing.T) {
+	store := &fakeOutbox{deleteFailures: 1}
+	store.add(t, "a")
+	b := broker.NewMemory()
+	received := acceptedOrders(t, b, "order.accepted")
+	relay := NewOutboxRelay(store, b, "order.accepted")
+
+	_, err := relay.RelayOnce(context.Background())
+	assert.ErrorContains(t, err, "delete published events")
+	require.Len(t, store.events, 1, "the event stays until its deletion succeeds")
+
+	_, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, store.events)
+	assert.Equal(t, []string{"a"}, received(), "the broker drops the repeat by its message ID")
+}
+
+// heldPublisher hands every publish ack to the test instead of sending it.
+type heldPublisher struct {
+	acks chan func(id string, err error)
+}
+
+func (p *heldPublisher) PublishAsync(channel, msgID string, data []byte, done func(id string, err error)) error {
+	p.acks <- done
+	return nil
+}
+
+func (p *heldPublisher) Close() error { return nil }
+
+func TestOutboxRelay_DeletesFromPostgresAfterAck(t *testing.T) {
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(repotest.DSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	repo.SetOutbox(true)
+	for _, uid := range []string{"a", "b"} {
+		var order model.Order
+		require.NoError(t, order.FromJSON(orderJSON(uid)))
+		require.NoError(t, repo.CreateOrder(ctx, &order))
+	}
+
+	publisher := &heldPublisher{acks: make(chan func(string, error), 2)}
+	relay := NewOutboxRelay(repo, publisher, "order.accepted")
+	result := make(chan error, 1)
+	go func() {
+		_, err := relay.RelayOnce(ctx)
+		result <- err
+	}()
+
+	ackA, ackB := <-publisher.acks, <-publisher.acks
+	pending, err := repo.PendingEvents(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2, "nothing is deleted before the broker acks")
+
+	ackA("1", nil)
+	ackB("", errors.New("nats: timeout"))
+	assert.ErrorContains(t, <-result, "not acknowledged")
+
+	pending, err = repo.PendingEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "only the acked event is deleted")
+	assert.Equal(t, "b", pending[0].OrderUID)
+}
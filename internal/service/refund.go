@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"order-service/internal/broker"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+)
+
+// ApplyRefund records a refund or cancellation and refreshes the cached
+// order. Events the order cannot accept fail at the validate stage.
+func (ns *NatsSubscriber) ApplyRefund(ctx context.Context, event *model.RefundEvent) (*model.Order, error) {
+	if err := event.Validate(); err != nil {
+		return nil, &StageError{Stage: model.StageValidate, Err: err}
+	}
+
+	order, err := ns.refunds.RefundOrder(ctx, event)
+	if rejectedRefund(err) {
+		return nil, &StageError{Stage: model.StageValidate, Err: err}
+	}
+	if err != nil {
+		return nil, &StageError{Stage: model.StagePersist, Err: err}
+	}
+
+	ns.cache.Set(order)
+	return order, nil
+}
+
+func decodeRefund(data []byte) (*model.RefundEvent, error) {
+	var event model.RefundEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, &StageError{Stage: model.StageDecode, Err: err}
+	}
+	return &event, nil
+}
+
+func rejectedRefund(err error) bool {
+	var transitionErr *model.TransitionError
+	return errors.As(err, &transitionErr) ||
+		errors.Is(err, repository.ErrRefundExceedsAmount) ||
+		errors.Is(err, repository.ErrUnknownItem) ||
+		errors.Is(err, repository.ErrItemAlreadyRefunded)
+}
+
+func (ns *NatsSubscriber) handleRefund(msg broker.Message) {
+	ns.mu.Lock()
+	if ns.closing {
+		ns.mu.Unlock()
+		return
+	}
+	ns.inflight.Add(1)
+	ns.mu.Unlock()
+	defer ns.inflight.Done()
+
+	event, err := decodeRefund(msg.Data())
+	if err != nil {
+		metrics.RefundEvents.WithLabelValues("rejected").Inc()
+		log.Printf("Refund event %d rejected: %v", msg.Sequence(), err)
+		ns.reject(msg, err, model.DeadLetterRefund)
+		return
+	}
+
+	order, err := ns.ApplyRefund(context.Background(), event)
+	switch {
+	case err == nil:
+		metrics.RefundEvents.WithLabelValues("applied").Inc()
+		log.Printf("Refund %s applied to order %s, %d of %d refunded",
+			event.RefundID, order.OrderUID, order.Payment.Refunded, order.Payment.Amount)
+		Ack(msg)
+	case errors.Is(err, repository.ErrDuplicateRefund):
+		metrics.RefundEvents.WithLabelValues("duplicate").Inc()
+		Ack(msg)
+	case Stage(err) != model.StagePersist:
+		metrics.RefundEvents.WithLabelValues("rejected").Inc()
+		log.Printf("Refund event %d rejected: %v", msg.Sequence(), err)
+		ns.reject(msg, err, model.DeadLetterRefund)
+	case errors.Is(err, repository.ErrOrderNotFound):
+		metrics.RefundEvents.WithLabelValues("unknown_order").Inc()
+		ns.delivery.RetryOrPark(msg, err, ns.parkAs(model.DeadLetterRefund))
+	default:
+		metrics.RefundEvents.WithLabelValues("error").Inc()
+		log.Printf("Failed to apply refund event %d: %v", msg.Sequence(), err)
+		ns.delivery.RetryOrPark(msg, err, ns.parkAs(model.DeadLetterRefund))
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"order-service/internal/cache"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"order-service/internal/repository/repotest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRefundStore struct {
+	mock.Mock
+}
+
+func (m *MockRefundStore) RefundOrder(ctx context.Context, event *model.RefundEvent) (*model.Order, error) {
+	args := m.Called(ctx, event)
+	order, _ := args.Get(0).(*model.Order)
+	return order, args.Error(1)
+}
+
+func TestNatsSubscriber_ApplyRefund(t *testing.T) {
+	refunded := &model.Order{OrderUID: "test123", Payment: model.Payment{Amount: 100, Refunded: 40}}
+	tests := []struct {
+		name     string
+		event    model.RefundEvent
+		storeErr error
+		stage    string
+	}{
+		{"applied", model.RefundEvent{RefundID: "r1", OrderUID: "test123", Type: model.RefundTypeRefund, Amount: 40}, nil, ""},
+		{"invalid", model.RefundEvent{OrderUID: "test123", Type: model.RefundTypeRefund}, nil, model.StageValidate},
+		{"exceeds amount", model.RefundEvent{RefundID: "r1", OrderUID: "test123", Type: model.RefundTypeRefund, Amount: 500},
+			repository.ErrRefundExceedsAmount, model.StageValidate},
+		{"database failure", model.RefundEvent{RefundID: "r1", OrderUID: "test123", Type: model.RefundTypeCancel},
+			errors.New("connection refused"), model.StagePersist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockRefundStore{}
+			if tt.storeErr != nil {
+				store.On("RefundOrder", mock.Anything, mock.Anything).Return(nil, tt.storeErr)
+			} else {
+				store.On("RefundOrder", mock.Anything, mock.Anything).Return(refunded, nil)
+			}
+			orders := cache.New()
+			subscriber := NewNatsSubscriber(&MockRepository{}, orders, nil)
+			subscriber.SetRefundStore(store)
+
+			_, err := subscriber.ApplyRefund(context.Background(), &tt.event)
+			if tt.stage == "" {
+				require.NoError(t, err)
+				cached, exists := orders.Get("test123")
+				require.True(t, exists)
+				assert.Equal(t, 40, cached.Payment.Refunded)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.stage, Stage(err))
+			assert.Equal(t, 0, orders.Size())
+		})
+	}
+}
+
+func TestNatsSubscriber_ParksAndReplaysRefunds(t *testing.T) {
+	store := &MockRefundStore{}
+	store.On("RefundOrder", mock.Anything, mock.Anything).Return(nil, repository.ErrRefundExceedsAmount).Twice()
+	store.On("RefundOrder", mock.Anything, mock.Anything).Return(&model.Order{OrderUID: "test123"}, nil)
+	deadLetters := &fakeDeadLetters{failures: 1}
+	subscriber, b, orders := newMemorySubscriber(t, &MockRepository{}, func(ns *NatsSubscriber) {
+		ns.SetRefundStore(store)
+		ns.SetDeadLetterStore(deadLetters)
+	})
+	require.NoError(t, subscriber.SubscribeRefunds("order-refunds"))
+
+	event := `{"refund_id": "r1", "order_uid": "test123", "type": "refund", "amount": 500}`
+	b.Publish("order-refunds", []byte(event))
+	b.Deliver()
+
+	// The first dead letter save fails, so the event is redelivered rather
+	// than dropped, and rejected again.
+	require.Len(t, deadLetters.saved, 1)
+	dl := deadLetters.saved[0]
+	assert.Equal(t, model.DeadLetterRefund, dl.Kind)
+	assert.Equal(t, "order-refunds", dl.Channel)
+	assert.Equal(t, model.StageValidate, dl.Stage)
+	assert.Equal(t, 0, b.Unacked("order-refunds"))
+
+	require.NoError(t, subscriber.Replay(context.Background(), dl.Kind, []byte(dl.Payload)))
+	_, exists := orders.Get("test123")
+	assert.True(t, exists, "replay applies the refund instead of ingesting it as an order")
+	store.AssertNumberOfCalls(t, "RefundOrder", 3)
+}
+
+func TestNatsSubscriber_CachesOrderWithoutIncomingRefunds(t *testing.T) {
+	repo, err := repository.NewPostgresRepository(repotest.DSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	_, b, orders := newMemorySubscriber(t, repo)
+
+	forged := strings.Replace(validOrderJSON, `"goods_total": 100`,
+		`"goods_total": 100, "refunded": 100, "refunds": [{"refund_id": "forged", "amount": 100}]`, 1)
+	forged = strings.Replace(forged, `"total_price": 100`, `"total_price": 100, "refunded": true`, 1)
+	b.Publish("orders", []byte(forged))
+	b.Deliver()
+
+	cached, ok := orders.Get("test123")
+	require.True(t, ok)
+	assert.Empty(t, cached.Payment.Refunds)
+	assert.Zero(t, cached.Payment.Refunded)
+	assert.False(t, cached.Items[0].Refunded)
+
+	stored, err := repo.GetOrderByUID(context.Background(), "test123")
+	require.NoError(t, err)
+	assert.Empty(t, stored.Payment.Refunds)
+	assert.False(t, stored.Items[0].Refunded)
+}
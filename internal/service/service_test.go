@@ -37,9 +37,17 @@ func TestOrderValidation(t *testing.T) {
 		Delivery: model.Delivery{
 			Name: "Test User",
 		},
+		Payment: model.Payment{
+			Transaction: "test123",
+			Currency:    "USD",
+			Amount:      100,
+			GoodsTotal:  100,
+		},
 		Items: []model.Item{
 			{
-				Name: "Test Item",
+				Name:        "Test Item",
+				TrackNumber: "TRACK123",
+				TotalPrice:  100,
 			},
 		},
 	}
@@ -66,4 +74,4 @@ func TestOrderJSON(t *testing.T) {
 	err = json.Unmarshal(data, &decodedOrder)
 	assert.NoError(t, err)
 	assert.Equal(t, order.OrderUID, decodedOrder.OrderUID)
-}
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"order-service/internal/broker"
+	"order-service/internal/metrics"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+)
+
+// ApplyStatus decodes a status event and applies it to the stored order,
+// refreshing the cached copy. A redelivered event that changes nothing
+// returns repository.ErrStatusUnchanged.
+func (ns *NatsSubscriber) ApplyStatus(ctx context.Context, data []byte) (*model.Order, error) {
+	var event model.StatusEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, &StageError{Stage: model.StageDecode, Err: err}
+	}
+	if err := event.Validate(); err != nil {
+		return nil, &StageError{Stage: model.StageValidate, Err: err}
+	}
+
+	order, err := ns.statuses.UpdateOrderStatus(ctx, &event)
+	var transitionErr *model.TransitionError
+	if errors.As(err, &transitionErr) {
+		return nil, &StageError{Stage: model.StageValidate, Err: err}
+	}
+	if err != nil {
+		return nil, &StageError{Stage: model.StagePersist, Err: err}
+	}
+
+	ns.cache.Set(order)
+	return order, nil
+}
+
+func (ns *NatsSubscriber) handleStatus(msg broker.Message) {
+	ns.mu.Lock()
+	if ns.closing {
+		ns.mu.Unlock()
+		return
+	}
+	ns.inflight.Add(1)
+	ns.mu.Unlock()
+	defer ns.inflight.Done()
+
+	order, err := ns.ApplyStatus(context.Background(), msg.Data())
+	switch {
+	case err == nil:
+		metrics.StatusEvents.WithLabelValues("applied").Inc()
+		log.Printf("Order %s is now %s", order.OrderUID, order.Status)
+		Ack(msg)
+	case errors.Is(err, repository.ErrStatusUnchanged):
+		metrics.StatusEvents.WithLabelValues("unchanged").Inc()
+		Ack(msg)
+	case Stage(err) != model.StagePersist:
+		metrics.StatusEvents.WithLabelValues("rejected").Inc()
+		log.Printf("Status event %d rejected: %v", msg.Sequence(), err)
+		ns.reject(msg, err, model.DeadLetterStatus)
+	case errors.Is(err, repository.ErrOrderNotFound):
+		// The order may still be on its way through the orders channel.
+		metrics.StatusEvents.WithLabelValues("unknown_order").Inc()
+		ns.delivery.RetryOrPark(msg, err, ns.parkAs(model.DeadLetterStatus))
+	default:
+		metrics.StatusEvents.WithLabelValues("error").Inc()
+		log.Printf("Failed to apply status event %d: %v", msg.Sequence(), err)
+		ns.delivery.RetryOrPark(msg, err, ns.parkAs(model.DeadLetterStatus))
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"order-service/internal/cache"
+	"order-service/internal/model"
+	"order-service/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockStatusStore struct {
+	mock.Mock
+}
+
+func (m *MockStatusStore) UpdateOrderStatus(ctx context.Context, event *model.StatusEvent) (*model.Order, error) {
+	args := m.Called(ctx, event)
+	order, _ := args.Get(0).(*model.Order)
+	return order, args.Error(1)
+}
+
+const paidEventJSON = `{"order_uid": "test123", "status": "paid", "occurred_at": "2021-11-26T06:22:19Z"}`
+
+func TestNatsSubscriber_ApplyStatusUpdatesCache(t *testing.T) {
+	store := &MockStatusStore{}
+	updated := &model.Order{OrderUID: "test123", Status: model.StatusPaid}
+	store.On("UpdateOrderStatus", mock.Anything, mock.MatchedBy(func(e *model.StatusEvent) bool {
+		return e.OrderUID == "test123" && e.Status == model.StatusPaid
+	})).Return(updated, nil)
+
+	orders := cache.New()
+	subscriber := NewNatsSubscriber(&MockRepository{}, orders, nil)
+	subscriber.SetStatusStore(store)
+
+	order, err := subscriber.ApplyStatus(context.Background(), []byte(paidEventJSON))
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusPaid, order.Status)
+
+	cached, exists := orders.Get("test123")
+	require.True(t, exists)
+	assert.Equal(t, model.StatusPaid, cached.Status)
+	store.AssertExpectations(t)
+}
+
+func TestNatsSubscriber_ApplyStatusStages(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		storeErr error
+		stage    string
+	}{
+		{"invalid json", `{"order_uid":`, nil, model.StageDecode},
+		{"unknown status", `{"order_uid": "test123", "status": "lost", "occurred_at": "2021-11-26T06:22:19Z"}`, nil, model.StageValidate},
+		{"invalid transition", paidEventJSON, &model.TransitionError{From: model.StatusCancelled, To: model.StatusPaid}, model.StageValidate},
+		{"unknown order", paidEventJSON, repository.ErrOrderNotFound, model.StagePersist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStatusStore{}
+			store.On("UpdateOrderStatus", mock.Anything, mock.Anything).Return(nil, tt.storeErr)
+			orders := cache.New()
+			subscriber := NewNatsSubscriber(&MockRepository{}, orders, nil)
+			subscriber.SetStatusStore(store)
+
+			_, err := subscriber.ApplyStatus(context.Background(), []byte(tt.data))
+			require.Error(t, err)
+			assert.Equal(t, tt.stage, Stage(err))
+			assert.Equal(t, 0, orders.Size())
+		})
+	}
+}
+
+func TestNatsSubscriber_ParksStatusEvents(t *testing.T) {
+	store := &MockStatusStore{}
+	store.On("UpdateOrderStatus", mock.Anything, mock.Anything).Return(nil, repository.ErrOrderNotFound)
+	deadLetters := &fakeDeadLetters{}
+	subscriber, b, _ := newMemorySubscriber(t, &MockRepository{}, func(ns *NatsSubscriber) {
+		ns.SetStatusStore(store)
+		ns.SetDeadLetterStore(deadLetters)
+	})
+	require.NoError(t, subscriber.SubscribeStatus("order-status"))
+
+	b.Publish("order-status", []byte(`{"order_uid":`))
+	b.Publish("order-status", []byte(paidEventJSON))
+	b.Deliver()
+
+	// The malformed event is parked right away, the one for an unknown order
+	// after its redeliveries.
+	require.Len(t, deadLetters.saved, 2)
+	for _, dl := range deadLetters.saved {
+		assert.Equal(t, model.DeadLetterStatus, dl.Kind)
+		assert.Equal(t, "order-status", dl.Channel)
+	}
+	assert.Equal(t, model.StageDecode, deadLetters.saved[0].Stage)
+	assert.Equal(t, model.StagePersist, deadLetters.saved[1].Stage)
+	assert.Equal(t, 0, b.Unacked("order-status"))
+}
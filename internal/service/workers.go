@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"order-service/internal/broker"
+	"order-service/internal/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WorkerPool handles messages on a fixed number of goroutines. Each message
+// is assigned to a worker by a hash of its key, so messages with the same key
+// are handled one after another in the order they were submitted, while
+// different keys proceed in parallel.
+type WorkerPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewWorkerPool starts workers goroutines sharing capacity queue slots.
+// Submit blocks once a worker's share is full, which holds back delivery
+// from the broker.
+func NewWorkerPool(workers, capacity int) *WorkerPool {
+	p := &WorkerPool{queues: make([]chan func(), workers)}
+	metrics.Workers.Set(float64(workers))
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), max(capacity/workers, 1))
+		p.wg.Add(1)
+		go p.run(i)
+	}
+	return p
+}
+
+func (p *WorkerPool) run(i int) {
+	defer p.wg.Done()
+
+	depth := metrics.WorkerQueueDepth.WithLabelValues(strconv.Itoa(i))
+	for job := range p.queues[i] {
+		depth.Dec()
+		metrics.WorkersBusy.Inc()
+		start := time.Now()
+		job()
+		metrics.WorkerBusySeconds.Add(time.Since(start).Seconds())
+		metrics.WorkersBusy.Dec()
+	}
+}
+
+// Submit queues job on the worker owning key and blocks while that worker's
+// queue is full. It reports false, without running job, once the pool is
+// stopped.
+func (p *WorkerPool) Submit(key string, job func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return false
+	}
+
+	i := partition(key, len(p.queues))
+	metrics.WorkerQueueDepth.WithLabelValues(strconv.Itoa(i)).Inc()
+	p.queues[i] <- job
+	return true
+}
+
+// Stop lets the workers finish the queued jobs and exit. It does not wait for
+// them; use Wait for that.
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	for _, q := range p.queues {
+		close(q)
+	}
+}
+
+// Wait blocks until the workers have exited after Stop.
+func (p *WorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+func partition(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// partitionKey returns the order_uid every order, status and refund message
+// carries. Messages without one are spread by sequence; they are rejected
+// anyway.
+func partitionKey(msg broker.Message) string {
+	var ref struct {
+		OrderUID string `json:"order_uid"`
+	}
+	if json.Unmarshal(msg.Data(), &ref) == nil && ref.OrderUID != "" {
+		return ref.OrderUID
+	}
+	return strconv.FormatUint(msg.Sequence(), 10)
+}
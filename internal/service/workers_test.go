@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_KeepsOrderPerKey(t *testing.T) {
+	pool := NewWorkerPool(4, 8)
+
+	var mu sync.Mutex
+	handled := make(map[string][]int)
+	for i := range 200 {
+		key := fmt.Sprintf("order-%d", i%7)
+		require.True(t, pool.Submit(key, func() {
+			mu.Lock()
+			handled[key] = append(handled[key], i)
+			mu.Unlock()
+		}))
+	}
+	pool.Stop()
+	pool.Wait()
+
+	total := 0
+	for key, seq := range handled {
+		assert.IsIncreasing(t, seq, "jobs for %s ran out of order", key)
+		total += len(seq)
+	}
+	assert.Equal(t, 200, total)
+	assert.False(t, pool.Submit("order-0", func() {}), "a stopped pool takes no jobs")
+}
+
+func TestWorkerPool_RunsKeysInParallel(t *testing.T) {
+	pool := NewWorkerPool(2, 2)
+	defer pool.Stop()
+
+	// Find a key on the other worker than "blocked".
+	other := "a"
+	for i := 0; partition(other, 2) == partition("blocked", 2); i++ {
+		other = fmt.Sprintf("key-%d", i)
+	}
+
+	release := make(chan struct{})
+	pool.Submit("blocked", func() { <-release })
+	done := make(chan struct{})
+	pool.Submit(other, func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a job on another worker waited for the blocked one")
+	}
+	close(release)
+}
+
+func TestNatsSubscriber_WorkerPool(t *testing.T) {
+	var mu sync.Mutex
+	stored := make(map[string]int)
+	mockRepo := &MockRepository{}
+	mockRepo.On("CreateOrder", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		mu.Lock()
+		stored[args.Get(1).(*model.Order).OrderUID]++
+		mu.Unlock()
+	})
+	pool := NewWorkerPool(3, 6)
+	subscriber, b, orders := newMemorySubscriber(t, mockRepo, func(ns *NatsSubscriber) {
+		ns.SetDeliveryOptions(DeliveryOptions{DurableName: "test", MaxInflight: 12, MaxRedeliveries: 2})
+		ns.SetWorkerPool(pool)
+	})
+
+	for i := range 12 {
+		b.Publish("orders", orderJSON(fmt.Sprintf("order-%d", i)))
+	}
+	assert.Equal(t, 12, b.Deliver())
+	pool.Stop()
+	pool.Wait()
+
+	assert.Len(t, stored, 12)
+	assert.Equal(t, 12, orders.Size())
+	assert.Equal(t, 0, b.Unacked("orders"))
+	require.NoError(t, subscriber.Shutdown(context.Background()))
+}
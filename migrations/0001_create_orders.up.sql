@@ -1,4 +1,4 @@
-CREATE TABLE orders (
+CREATE TABLE IF NOT EXISTS orders (
     order_uid VARCHAR(255) PRIMARY KEY,
     track_number VARCHAR(255) NOT NULL,
     entry VARCHAR(50) NOT NULL,
@@ -12,7 +12,7 @@ CREATE TABLE orders (
     oof_shard VARCHAR(10) NOT NULL
 );
 
-CREATE TABLE delivery (
+CREATE TABLE IF NOT EXISTS delivery (
     order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
     name VARCHAR(255) NOT NULL,
     phone VARCHAR(50) NOT NULL,
@@ -23,7 +23,7 @@ CREATE TABLE delivery (
     email VARCHAR(255) NOT NULL
 );
 
-CREATE TABLE payment (
+CREATE TABLE IF NOT EXISTS payment (
     order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
     transaction VARCHAR(255) NOT NULL,
     request_id VARCHAR(255),
@@ -37,7 +37,7 @@ CREATE TABLE payment (
     custom_fee INTEGER NOT NULL
 );
 
-CREATE TABLE items (
+CREATE TABLE IF NOT EXISTS items (
     id SERIAL PRIMARY KEY,
     order_uid VARCHAR(255) REFERENCES orders(order_uid) ON DELETE CASCADE,
     chrt_id INTEGER NOT NULL,
@@ -53,5 +53,6 @@ CREATE TABLE items (
     status INTEGER NOT NULL
 );
 
-CREATE INDEX idx_orders_order_uid ON orders(order_uid);
-CREATE INDEX idx_items_order_uid ON items(order_uid);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_orders_order_uid ON orders(order_uid);
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items(order_uid);
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders(date_created DESC, order_uid DESC);
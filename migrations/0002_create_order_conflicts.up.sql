@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS order_conflicts (
+    id SERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_conflicts_order_uid ON order_conflicts(order_uid);
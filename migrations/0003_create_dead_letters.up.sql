@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    payload BYTEA NOT NULL,
+    sequence BIGINT NOT NULL,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    stage VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    replayed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_stage ON dead_letters(stage, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_customer_id;
+DROP INDEX IF EXISTS idx_orders_track_number;
+DROP INDEX IF EXISTS idx_orders_delivery_service;
+DROP INDEX IF EXISTS idx_payment_provider;
+DROP INDEX IF EXISTS idx_payment_currency;
+DROP INDEX IF EXISTS idx_items_brand;
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service ON orders(delivery_service, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_payment_provider ON payment(provider);
+CREATE INDEX IF NOT EXISTS idx_payment_currency ON payment(currency);
+CREATE INDEX IF NOT EXISTS idx_items_brand ON items(brand, order_uid);
@@ -0,0 +1,14 @@
+ALTER TABLE orders ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'created';
+
+CREATE TABLE order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_order_status_history_order_uid ON order_status_history(order_uid, id);
+
+INSERT INTO order_status_history (order_uid, status, changed_at)
+SELECT order_uid, 'created', date_created FROM orders;
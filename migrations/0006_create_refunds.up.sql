@@ -0,0 +1,18 @@
+CREATE TABLE refunds (
+    id BIGSERIAL PRIMARY KEY,
+    refund_id VARCHAR(255) NOT NULL UNIQUE,
+    order_uid VARCHAR(255) NOT NULL REFERENCES payment(order_uid) ON DELETE CASCADE,
+    amount INTEGER NOT NULL CHECK (amount >= 0),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE refund_items (
+    refund_id BIGINT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    order_uid VARCHAR(255) NOT NULL,
+    rid VARCHAR(255) NOT NULL,
+    PRIMARY KEY (order_uid, rid)
+);
+
+CREATE INDEX idx_refunds_order_uid ON refunds(order_uid, id);
+CREATE INDEX idx_refund_items_refund_id ON refund_items(refund_id);
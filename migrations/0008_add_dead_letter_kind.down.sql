@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_dead_letters_kind;
+ALTER TABLE dead_letters DROP COLUMN kind, DROP COLUMN channel;
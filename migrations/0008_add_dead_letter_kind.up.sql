@@ -0,0 +1,5 @@
+ALTER TABLE dead_letters
+    ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'order',
+    ADD COLUMN channel VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_dead_letters_kind ON dead_letters(kind, id);
@@ -0,0 +1,8 @@
+// Package migrations embeds the numbered schema migrations so they ship with
+// the binary. Files are named NNNN_description.up.sql / .down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
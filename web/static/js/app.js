@@ -110,7 +110,8 @@ class OrderService {
                             { label: 'Provider', value: order.payment.provider },
                             { label: 'Bank', value: order.payment.bank },
                             { label: 'Delivery Cost', value: `$${order.payment.delivery_cost}` },
-                            { label: 'Goods Total', value: `$${order.payment.goods_total}` }
+                            { label: 'Goods Total', value: `$${order.payment.goods_total}` },
+                            { label: 'Refunded', value: order.payment.refunded ? `$${order.payment.refunded}` : '' }
                         ])}
                     </div>
                 </div>
@@ -133,13 +134,40 @@ class OrderService {
                         </thead>
                         <tbody>
                             ${order.items.map(item => `
-                                <tr>
+                                <tr class="${item.refunded ? 'refunded' : ''}">
                                     <td><strong>${item.name}</strong></td>
                                     <td>${item.brand}</td>
                                     <td>$${item.price}</td>
                                     <td>${item.sale}%</td>
                                     <td class="amount">$${item.total_price}</td>
-                                    <td><span class="status-badge status-delivered">${this.getItemStatus(item.status)}</span></td>
+                                    <td>${item.refunded
+                                        ? '<span class="status-badge status-cancelled">Refunded</span>'
+                                        : `<span class="status-badge status-delivered">${this.getItemStatus(item.status)}</span>`}</td>
+                                </tr>
+                            `).join('')}
+                        </tbody>
+                    </table>
+                </div>
+
+                <!-- Status History -->
+                <div class="section">
+                    <div class="section-title">
+                        Status History
+                    </div>
+                    <table class="items-table">
+                        <thead>
+                            <tr>
+                                <th>Status</th>
+                                <th>Changed At</th>
+                                <th>Reason</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+                            ${(order.status_history || []).map(change => `
+                                <tr>
+                                    <td>${this.getStatusBadge({ status: change.status })}</td>
+                                    <td>${new Date(change.changed_at).toLocaleString()}</td>
+                                    <td>${this.escapeHTML(change.reason || '')}</td>
                                 </tr>
                             `).join('')}
                         </tbody>
@@ -175,8 +203,24 @@ class OrderService {
         `).join('');
     }
 
+    escapeHTML(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML;
+    }
+
     getStatusBadge(order) {
-        return '<span class="status-badge status-delivered">Completed</span>';
+        const statusMap = {
+            created: { label: 'Created', className: 'status-pending' },
+            paid: { label: 'Paid', className: 'status-pending' },
+            assembling: { label: 'Assembling', className: 'status-pending' },
+            shipped: { label: 'Shipped', className: 'status-pending' },
+            delivered: { label: 'Delivered', className: 'status-delivered' },
+            cancelled: { label: 'Cancelled', className: 'status-cancelled' },
+            returned: { label: 'Returned', className: 'status-cancelled' }
+        };
+        const status = statusMap[order.status] || { label: 'Unknown', className: 'status-pending' };
+        return `<span class="status-badge ${status.className}">${status.label}</span>`;
     }
 
     getItemStatus(statusCode) {